@@ -0,0 +1,107 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrEmptyPrefix Returned by DeleteByIDPrefix for an empty prefix, which would match every session
+var ErrEmptyPrefix = errors.New("gorm session: empty sid prefix")
+
+// DeleteByIDPrefix Deletes every session whose sid starts with prefix, ignoring case,
+// e.g. to purge the sessions of load and integration tests, returns the number of deleted sessions
+func (s *ManagerStore) DeleteByIDPrefix(ctx context.Context, prefix string) (int64, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	} else if prefix == "" {
+		return 0, ErrEmptyPrefix
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// the fallback rows of the deleted sessions go with them, so the second
+	// walk only finds the sessions that exist in the fallback table alone
+	deleted, err := s.deletePrefixed(s.db, prefix, func(keys []string) (int64, error) {
+		n, err := s.deleteIDs(keys)
+		if err != nil || s.fallbackTable == "" {
+			return n, err
+		}
+		return n, s.db.Table(s.fallbackTable).Where("id IN (?)", keys).Delete(nil).Error
+	})
+	if err != nil || s.fallbackTable == "" {
+		return deleted, err
+	}
+
+	fallback := s.db.Table(s.fallbackTable)
+	n, err := s.deletePrefixed(fallback, prefix, func(keys []string) (int64, error) {
+		result := fallback.Where("id IN (?)", keys).Delete(nil)
+		return result.RowsAffected, result.Error
+	})
+	return deleted + n, err
+}
+
+// deletePrefixed Walks the rows of db whose sid starts with prefix, ignoring case, in primary
+// key order and deletes them by del batchSize at a time, reporting them as deleted sessions;
+// the SIDPrefix of the store is matched exactly, the values and the other rows of the sessions
+// are cleaned by GC as those of the expired ones
+func (s *ManagerStore) deletePrefixed(db *gorm.DB, prefix string, del func(keys []string) (int64, error)) (int64, error) {
+	var last string
+	var deleted int64
+	for {
+		var keys []string
+		err := db.Where("id>? AND id LIKE ? ESCAPE '!' AND LOWER(id) LIKE LOWER(?) ESCAPE '!'",
+			last, escapeLike(s.idPrefix)+"%", escapeLike(s.key(prefix))+"%").
+			Order("id").Limit(batchSize).Pluck("id", &keys).Error
+		if err != nil || len(keys) == 0 {
+			return deleted, err
+		}
+		full := len(keys) == batchSize
+		last = keys[len(keys)-1]
+
+		// LIKE ignores case for ASCII in some dialects, the SIDPrefix must match exactly
+		matched := keys[:0]
+		for _, key := range keys {
+			if strings.HasPrefix(key, s.idPrefix) {
+				matched = append(matched, key)
+			}
+		}
+
+		if err := s.deleteKeys(matched, del, &deleted); err != nil || !full {
+			return deleted, err
+		}
+	}
+}
+
+// deleteKeys Buries the rows keys and deletes them by del, adding the deleted rows
+// to deleted, and reports the sessions as deleted
+func (s *ManagerStore) deleteKeys(keys []string, del func(keys []string) (int64, error), deleted *int64) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	for _, key := range keys {
+		if err := s.bury(key); err != nil {
+			return err
+		}
+	}
+
+	n, err := del(keys)
+	*deleted += n
+	if err != nil || n == 0 {
+		return err
+	}
+	for _, key := range keys {
+		sid := s.sessionID(key)
+		if err := s.notifyPeers("delete", sid); err != nil {
+			return err
+		}
+		s.fireDelete(sid)
+	}
+	return nil
+}
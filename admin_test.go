@@ -0,0 +1,92 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeleteByIDPrefix(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_admin"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test purging sessions by sid prefix", t, func() {
+		ctx := context.Background()
+		sids := []string{"LoadTest_" + newSid(), "loadtest_" + newSid(), "loadtest%" + newSid(), newSid()}
+		defer mstore.Delete(ctx, sids[3])
+		for _, sid := range sids {
+			store, err := mstore.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		_, err := mstore.DeleteByIDPrefix(ctx, "")
+		So(err, ShouldEqual, ErrEmptyPrefix)
+
+		deleted, err := mstore.DeleteByIDPrefix(ctx, "loadtest_")
+		So(err, ShouldBeNil)
+		So(deleted, ShouldEqual, 2)
+
+		checked, err := mstore.CheckMany(ctx, sids)
+		So(err, ShouldBeNil)
+		So(checked, ShouldResemble, map[string]bool{sids[0]: false, sids[1]: false, sids[2]: true, sids[3]: true})
+
+		deleted, err = mstore.DeleteByIDPrefix(ctx, "loadtest%")
+		So(err, ShouldBeNil)
+		So(deleted, ShouldEqual, 1)
+	})
+}
+
+func TestDeleteByIDPrefixSIDPrefix(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	var deletedSids []string
+	mstore, err := NewManagerStore(Config{
+		TableName: "session_admin_shared",
+		SIDPrefix: "app:",
+		OnDelete:  func(sid string) { deletedSids = append(deletedSids, sid) },
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	other, err := NewManagerStore(Config{TableName: "session_admin_shared", SIDPrefix: "APP:"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer other.Close()
+
+	Convey("Test purging sessions by sid prefix leaves other SIDPrefix stores alone", t, func() {
+		ctx := context.Background()
+		sid := "loadtest_" + newSid()
+		for _, s := range []*ManagerStore{mstore, other} {
+			store, err := s.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+		defer other.Delete(ctx, sid)
+
+		deleted, err := mstore.DeleteByIDPrefix(ctx, "LOADTEST_")
+		So(err, ShouldBeNil)
+		So(deleted, ShouldEqual, 1)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+		exists, err = other.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		mstore.Close()
+		So(deletedSids, ShouldResemble, []string{sid})
+	})
+}
@@ -0,0 +1,143 @@
+package gorm
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultAnalyzeInterval The time between two content analyses of GC by default
+const defaultAnalyzeInterval = time.Hour
+
+// KeyStats The use of a session key in the sampled sessions
+type KeyStats struct {
+	Key      string  `json:"key"`
+	Sessions int     `json:"sessions"` // Sampled sessions holding the key
+	Bytes    int64   `json:"bytes"`    // Serialized size of the key and its values
+	Share    float64 `json:"share"`    // Fraction of the bytes of all sampled sessions
+}
+
+// ContentReport The keys of a random sample of the live sessions, by their size
+type ContentReport struct {
+	Recorded time.Time  `json:"recorded_at"`
+	Sampled  int        `json:"sampled"`
+	Bytes    int64      `json:"bytes"`
+	Keys     []KeyStats `json:"keys"`
+}
+
+// ContentStats Returns the report of the last content analysis of GC,
+// nil before the first one or without Config.AnalyzeSample
+func (s *ManagerStore) ContentStats() *ContentReport {
+	root := s.root()
+	root.contentMu.Lock()
+	defer root.contentMu.Unlock()
+	return root.content
+}
+
+// AnalyzeContent Samples up to n live sessions of the store's sid prefix at random and reports
+// the frequency and size of their keys, e.g. "cart_items accounts for 71% of session bytes"
+func (s *ManagerStore) AnalyzeContent(ctx context.Context, n int) (*ContentReport, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.analyzeContent(n)
+}
+
+// analyzeContent Samples up to n live sessions and reports their keys
+func (s *ManagerStore) analyzeContent(n int) (*ContentReport, error) {
+	var items []*SessionItem
+	err := s.scoped().Where("expired_at>?", time.Now()).
+		Order(s.randomOrder()).Limit(n).Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	if s.separateValues && len(items) > 0 {
+		if err := s.loadValues(items); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &ContentReport{Recorded: time.Now()}
+	keys := make(map[string]*KeyStats)
+	for _, item := range items {
+		values, _, err := s.decodeValues(item.Value)
+		if err != nil {
+			continue
+		}
+
+		report.Sampled++
+		for key, value := range values {
+			buf, err := jsonMarshal(value)
+			if err != nil {
+				continue
+			}
+
+			stats, ok := keys[key]
+			if !ok {
+				stats = &KeyStats{Key: key}
+				keys[key] = stats
+			}
+			size := int64(len(strconv.Quote(key)) + 1 + len(buf))
+			stats.Sessions++
+			stats.Bytes += size
+			report.Bytes += size
+		}
+	}
+
+	for _, stats := range keys {
+		if report.Bytes > 0 {
+			stats.Share = float64(stats.Bytes) / float64(report.Bytes)
+		}
+		report.Keys = append(report.Keys, *stats)
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		if report.Keys[i].Bytes != report.Keys[j].Bytes {
+			return report.Keys[i].Bytes > report.Keys[j].Bytes
+		}
+		return report.Keys[i].Key < report.Keys[j].Key
+	})
+	return report, nil
+}
+
+// randomOrder Returns the ORDER BY expression of a random row order of the dialect
+func (s *ManagerStore) randomOrder() string {
+	switch s.db.Dialect().GetName() {
+	case "mysql":
+		return "RAND()"
+	case "mssql":
+		return "NEWID()"
+	}
+	return "RANDOM()"
+}
+
+// analyzeIfDue Runs the content analysis of GC once the interval since the last one passed
+func (s *ManagerStore) analyzeIfDue(gs *ManagerStore) error {
+	interval := s.analyzeInterval
+	if interval <= 0 {
+		interval = defaultAnalyzeInterval
+	}
+
+	s.contentMu.Lock()
+	due := s.content == nil || time.Since(s.content.Recorded) >= interval
+	s.contentMu.Unlock()
+	if !due {
+		return nil
+	}
+
+	report, err := gs.analyzeContent(s.analyzeSample)
+	if err != nil {
+		return err
+	}
+
+	s.contentMu.Lock()
+	s.content = report
+	s.contentMu.Unlock()
+	return nil
+}
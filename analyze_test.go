@@ -0,0 +1,62 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAnalyzeContent(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_analyze", AnalyzeSample: 10}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test sampling the keys of the live sessions", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		So(mstore.ContentStats(), ShouldBeNil)
+
+		for i := 0; i < 3; i++ {
+			store, err := mstore.Create(ctx, newSid(), 60)
+			So(err, ShouldBeNil)
+			store.Set("user", "foo")
+			if i == 0 {
+				store.Set("cart_items", []string{"apple", "banana", "cherry", "damson", "elderberry"})
+			}
+			So(store.Save(), ShouldBeNil)
+		}
+
+		report, err := mstore.AnalyzeContent(ctx, 2)
+		So(err, ShouldBeNil)
+		So(report.Sampled, ShouldEqual, 2)
+
+		report, err = mstore.AnalyzeContent(ctx, 10)
+		So(err, ShouldBeNil)
+		So(report.Sampled, ShouldEqual, 3)
+		So(report.Keys, ShouldHaveLength, 2)
+		So(report.Keys[0].Key, ShouldEqual, "cart_items")
+		So(report.Keys[0].Sessions, ShouldEqual, 1)
+		So(report.Keys[1].Key, ShouldEqual, "user")
+		So(report.Keys[1].Sessions, ShouldEqual, 3)
+		So(report.Keys[0].Bytes+report.Keys[1].Bytes, ShouldEqual, report.Bytes)
+		So(report.Keys[0].Share, ShouldBeGreaterThan, 0.5)
+
+		mstore.runGC()
+		content := mstore.ContentStats()
+		So(content, ShouldNotBeNil)
+		So(content.Sampled, ShouldEqual, 3)
+
+		mstore.runGC()
+		So(mstore.ContentStats(), ShouldEqual, content)
+
+		status, err := mstore.Status(ctx)
+		So(err, ShouldBeNil)
+		So(status.Content, ShouldEqual, content)
+	})
+}
@@ -0,0 +1,120 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrAttemptsDisabled Returned by the attempt operations unless Config.EnableAttempts is set
+var ErrAttemptsDisabled = errors.New("gorm session: attempt tracking is not enabled")
+
+// attemptItem Counter of a session, e.g. of failed logins, incremented by the database
+// so that concurrent increments of several instances are not lost
+type attemptItem struct {
+	SessionID string    `gorm:"column:session_id;size:255;primary_key;"`
+	Name      string    `gorm:"column:name;size:64;primary_key;"`
+	Count     int       `gorm:"column:count;"`
+	UpdatedAt time.Time `gorm:"column:updated_at;"`
+}
+
+// attemptTable Returns the name of the attempt table of a session table
+func attemptTable(tableName string) string {
+	return tableName + "_attempts"
+}
+
+// attempts Returns the db of the attempt table
+func (s *ManagerStore) attempts() *gorm.DB {
+	return s.db.Table(attemptTable(s.tableName))
+}
+
+// cleanAttempts Deletes the counters whose session row is gone
+func (s *ManagerStore) cleanAttempts() error {
+	cond := fmt.Sprintf("session_id NOT IN (SELECT id FROM %s)", s.db.NewScope(nil).Quote(s.tableName))
+	return s.attempts().Where(cond).Delete(nil).Error
+}
+
+// IncrementAttempt Increments the counter key of the session sid, e.g. the failed logins,
+// and returns its value. Concurrent increments may return the same, later value,
+// the count is never lower than the increments made.
+func (s *ManagerStore) IncrementAttempt(ctx context.Context, sid, key string) (int, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	} else if !s.attemptsEnabled {
+		return 0, ErrAttemptsDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	id := s.key(sid)
+	for i := 0; ; i++ {
+		result := s.attempts().Where("session_id=? AND name=?", id, key).
+			Updates(map[string]interface{}{
+				"count":      gorm.Expr("count+1"),
+				"updated_at": time.Now(),
+			})
+		if err := result.Error; err != nil {
+			return 0, err
+		} else if result.RowsAffected > 0 {
+			break
+		}
+
+		err := s.attempts().Create(&attemptItem{SessionID: id, Name: key, Count: 1, UpdatedAt: time.Now()}).Error
+		if err == nil {
+			return 1, nil
+		} else if i > 0 {
+			// the counter was created concurrently, but the update still does not find it
+			return 0, err
+		}
+	}
+
+	var item attemptItem
+	err = s.attempts().Where("session_id=? AND name=?", id, key).First(&item).Error
+	return item.Count, err
+}
+
+// Attempts Returns the counter key of the session sid, 0 if it was never incremented
+func (s *ManagerStore) Attempts(ctx context.Context, sid, key string) (int, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	} else if !s.attemptsEnabled {
+		return 0, ErrAttemptsDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var item attemptItem
+	err = s.attempts().Where("session_id=? AND name=?", s.key(sid), key).First(&item).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	return item.Count, err
+}
+
+// ResetAttempts Resets the counter key of the session sid, e.g. after a successful login
+func (s *ManagerStore) ResetAttempts(ctx context.Context, sid, key string) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	} else if !s.attemptsEnabled {
+		return ErrAttemptsDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.attempts().Where("session_id=? AND name=?", s.key(sid), key).Delete(nil).Error
+}
@@ -0,0 +1,92 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAttempts(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_attempts", EnableAttempts: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test attempt counters incremented by the database", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("user", "alice")
+		So(store.Save(), ShouldBeNil)
+
+		n, err := mstore.Attempts(ctx, sid, "login")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+
+		n, err = mstore.IncrementAttempt(ctx, sid, "login")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := mstore.IncrementAttempt(ctx, sid, "login")
+				if err != nil {
+					t.Error(err.Error())
+				}
+			}()
+		}
+		wg.Wait()
+
+		n, err = mstore.Attempts(ctx, sid, "login")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 9)
+
+		n, err = mstore.IncrementAttempt(ctx, sid, "otp")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+
+		So(mstore.ResetAttempts(ctx, sid, "login"), ShouldBeNil)
+		n, err = mstore.Attempts(ctx, sid, "login")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+
+		// the counters follow the session to its new id
+		newsid := newSid()
+		defer mstore.Delete(ctx, newsid)
+		_, err = mstore.Refresh(ctx, sid, newsid, 60)
+		So(err, ShouldBeNil)
+		n, err = mstore.Attempts(ctx, newsid, "otp")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+		n, err = mstore.Attempts(ctx, sid, "otp")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+
+		So(mstore.Delete(ctx, newsid), ShouldBeNil)
+		n, err = mstore.Attempts(ctx, newsid, "otp")
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+	})
+
+	Convey("Test attempt counters when not enabled", t, func() {
+		mstore, err := NewManagerStore(Config{TableName: "session_attempts_off"}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		_, err = mstore.IncrementAttempt(context.Background(), newSid(), "login")
+		So(err, ShouldEqual, ErrAttemptsDisabled)
+		So(mstore.ResetAttempts(context.Background(), newSid(), "login"), ShouldEqual, ErrAttemptsDisabled)
+	})
+}
@@ -0,0 +1,84 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrAuthenticationDisabled Returned by the authentication operations unless Config.TrackAuthentication is set
+var ErrAuthenticationDisabled = errors.New("gorm session: authentication tracking is not enabled")
+
+// authenticatedColumn Used to migrate the authenticated_at column onto the session table
+type authenticatedColumn struct {
+	AuthenticatedAt *time.Time `gorm:"column:authenticated_at;"`
+}
+
+// MarkAuthenticated Records that the user of the session sid authenticated at the given time,
+// the time is kept through Refresh
+func (s *ManagerStore) MarkAuthenticated(ctx context.Context, sid string, at time.Time) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	} else if !s.trackAuth {
+		return ErrAuthenticationDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(sid)
+	result := s.db.Where("id=?", key).Update("authenticated_at", at)
+	if err := result.Error; err != nil || result.RowsAffected > 0 {
+		return err
+	}
+
+	// some drivers count unchanged rows as unaffected
+	exists, err := s.exists(s.db, key)
+	if err == nil && !exists {
+		err = ErrSessionNotFound
+	}
+	return err
+}
+
+// AuthenticatedAt Returns the time recorded by MarkAuthenticated for the session sid,
+// the zero time if there is none, and ErrSessionNotFound if the session does not exist
+func (s *ManagerStore) AuthenticatedAt(ctx context.Context, sid string) (time.Time, error) {
+	if s.isClosed() {
+		return time.Time{}, ErrStoreClosed
+	} else if !s.trackAuth {
+		return time.Time{}, ErrAuthenticationDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	key := s.key(sid)
+	at, err := s.authenticatedAt(key)
+	if err != nil {
+		return time.Time{}, err
+	} else if at == nil {
+		exists, err := s.exists(s.db, key)
+		if err == nil && !exists {
+			err = ErrSessionNotFound
+		}
+		return time.Time{}, err
+	}
+	return *at, nil
+}
+
+func (s *ManagerStore) authenticatedAt(key string) (*time.Time, error) {
+	var column authenticatedColumn
+	err := s.db.Where("id=?", key).Select("authenticated_at").Scan(&column).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return column.AuthenticatedAt, nil
+}
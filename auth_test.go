@@ -0,0 +1,51 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreserveCreatedAt(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_auth", PreserveCreatedAt: true, TrackAuthentication: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test carrying the login time through Refresh", t, func() {
+		ctx := context.Background()
+		sid, newsid := newSid(), newSid()
+		defer mstore.Delete(ctx, newsid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		login := time.Now().Add(-time.Hour).Truncate(time.Second)
+		So(mstore.db.Where("id=?", sid).Update("created_at", login).Error, ShouldBeNil)
+		So(mstore.MarkAuthenticated(ctx, sid, login), ShouldBeNil)
+
+		_, err = mstore.Refresh(ctx, sid, newsid, 60)
+		So(err, ShouldBeNil)
+
+		var item SessionItem
+		So(mstore.db.Where("id=?", newsid).First(&item).Error, ShouldBeNil)
+		So(item.CreatedAt.Equal(login), ShouldBeTrue)
+
+		at, err := mstore.AuthenticatedAt(ctx, newsid)
+		So(err, ShouldBeNil)
+		So(at.Equal(login), ShouldBeTrue)
+
+		at, err = mstore.AuthenticatedAt(ctx, newSid())
+		So(err, ShouldEqual, ErrSessionNotFound)
+		So(at.IsZero(), ShouldBeTrue)
+		So(mstore.MarkAuthenticated(ctx, newSid(), login), ShouldEqual, ErrSessionNotFound)
+	})
+}
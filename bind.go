@@ -0,0 +1,115 @@
+package gorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// bindField Exported struct field mapped to a session key
+type bindField struct {
+	index     int
+	key       string
+	omitEmpty bool
+}
+
+// bindFields Returns the fields of a struct type mapped to session keys,
+// the key is taken from the session tag or the field name, a "-" tag skips the field
+func bindFields(typ reflect.Type) []bindField {
+	var fields []bindField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := field.Tag.Get("session")
+		if tag == "-" {
+			continue
+		}
+
+		f := bindField{index: i, key: field.Name}
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			f.key = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				f.omitEmpty = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// structValue Returns the struct that v points to or is
+func structValue(v interface{}, ptr bool) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	} else if ptr {
+		return reflect.Value{}, fmt.Errorf("gorm session: bind requires a pointer to a struct, got %T", v)
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("gorm session: bind requires a struct, got %T", v)
+	}
+	return rv, nil
+}
+
+// Bind Copies the session values into the fields of the struct dst points to,
+// e.g. `session:"user_id"` binds the user_id value, fields without a value are left unchanged
+func (s *Store) Bind(dst interface{}) error {
+	rv, err := structValue(dst, true)
+	if err != nil {
+		return err
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, f := range bindFields(rv.Type()) {
+		v, ok := s.values[f.key]
+		if !ok {
+			continue
+		}
+
+		// loaded values are decoded from json, so they are converted the same way
+		buf, err := jsonMarshal(v)
+		if err != nil {
+			return err
+		}
+		field := rv.Field(f.index)
+		err = jsonUnmarshal(buf, field.Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("gorm session: bind %s: %v", f.key, err)
+		}
+	}
+	return nil
+}
+
+// Write Sets the session values from the fields of the struct src,
+// the reverse of Bind, a zero field tagged omitempty deletes its value
+func (s *Store) Write(src interface{}) error {
+	rv, err := structValue(src, false)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, f := range bindFields(rv.Type()) {
+		field := rv.Field(f.index)
+		if f.omitEmpty && isZero(field) {
+			delete(s.values, f.key)
+			continue
+		}
+		s.values[f.key] = field.Interface()
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,59 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type bindView struct {
+	UserID  int64    `session:"user_id"`
+	Name    string   `session:"name"`
+	Roles   []string `session:"roles"`
+	Theme   string   `session:"theme,omitempty"`
+	Ignored string   `session:"-"`
+	Plain   bool
+}
+
+func TestBind(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_bind"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test binding session values into a struct", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		gstore := store.(*Store)
+		gstore.Set("theme", "dark")
+
+		err = gstore.Write(bindView{UserID: 42, Name: "foo", Roles: []string{"admin"}, Ignored: "x", Plain: true})
+		So(err, ShouldBeNil)
+		_, ok := store.Get("theme")
+		So(ok, ShouldBeFalse)
+		_, ok = store.Get("Ignored")
+		So(ok, ShouldBeFalse)
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+
+		var view bindView
+		So(store.(*Store).Bind(&view), ShouldBeNil)
+		So(view, ShouldResemble, bindView{UserID: 42, Name: "foo", Roles: []string{"admin"}, Plain: true})
+
+		So(store.(*Store).Bind(view), ShouldNotBeNil)
+
+		store.Set("user_id", "not a number")
+		So(store.(*Store).Bind(&view), ShouldNotBeNil)
+	})
+}
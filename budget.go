@@ -0,0 +1,128 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded Returned by store operations once the budget of their context is spent
+var ErrBudgetExceeded = errors.New("gorm session: time budget exceeded")
+
+type budgetKey struct{}
+
+// budget Time left for the store operations of one request
+type budget struct {
+	sync.Mutex
+	remaining time.Duration
+	running   int                  // operations in progress, nested or concurrent
+	since     time.Time            // start of the earliest operation in progress
+	cancels   []context.CancelFunc // of the query contexts of the operations in progress
+}
+
+// WithBudget Returns a context that caps the combined time of all store operations
+// using it at d, e.g. per request, once it is spent they fail fast with ErrBudgetExceeded.
+// The queries of an operation are canceled when the budget runs out.
+func WithBudget(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &budget{remaining: d})
+}
+
+// budgetOf Returns the budget of ctx, nil if it has none
+func budgetOf(ctx context.Context) *budget {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(budgetKey{}).(*budget)
+	return b
+}
+
+// left Returns the time left, minus the time of the operations in progress
+func (b *budget) left() time.Duration {
+	if b.running == 0 {
+		return b.remaining
+	}
+	return b.remaining - time.Since(b.since)
+}
+
+// checkBudget Returns ErrBudgetExceeded if the budget of ctx is spent
+func checkBudget(ctx context.Context) error {
+	b := budgetOf(ctx)
+	if b == nil {
+		return nil
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	if b.left() <= 0 {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// charge Starts charging an operation to the budget of ctx and returns the function
+// that stops it. The budget is charged once for the time any operation runs, so a
+// public operation calling another, e.g. RefreshWith calling Refresh and Save,
+// or concurrent operations of one request are not charged twice
+func charge(ctx context.Context) func() {
+	b := budgetOf(ctx)
+	if b == nil {
+		return func() {}
+	}
+
+	b.Lock()
+	if b.running == 0 {
+		b.since = time.Now()
+	}
+	b.running++
+	b.Unlock()
+
+	return func() {
+		b.Lock()
+		defer b.Unlock()
+
+		b.running--
+		if b.running > 0 {
+			return
+		}
+		b.remaining -= time.Since(b.since)
+		for _, cancel := range b.cancels {
+			cancel()
+		}
+		b.cancels = nil
+	}
+}
+
+// bind Returns a copy of the store whose queries are canceled once the budget of ctx
+// runs out, the store itself if ctx has no budget or no operation is charged to it
+func (s *ManagerStore) bind(ctx context.Context) *ManagerStore {
+	b := budgetOf(ctx)
+	if b == nil {
+		return s
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	if b.running == 0 {
+		return s
+	}
+
+	// released once no operation of the budget runs any more
+	qctx, cancel := context.WithDeadline(ctx, b.since.Add(b.remaining))
+	b.cancels = append(b.cancels, cancel)
+
+	bound := s.unbind().withContext(qctx)
+	if bound != s.unbind() {
+		bound.unbound = s.unbind()
+	}
+	return bound
+}
+
+// unbind Returns the store a budget-bound copy was made of, the store itself otherwise,
+// e.g. for a session that outlives the operation that loaded it
+func (s *ManagerStore) unbind() *ManagerStore {
+	if s.unbound != nil {
+		return s.unbound
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithBudget(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_budget"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test a time budget shared by the operations of a request", t, func() {
+		ctx := WithBudget(context.Background(), time.Minute)
+		sid := newSid()
+		defer mstore.Delete(context.Background(), sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		ctx = WithBudget(context.Background(), time.Minute)
+		_, err = mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+
+		budgetOf(ctx).remaining = 0
+		_, err = mstore.Check(ctx, sid)
+		So(err, ShouldEqual, ErrBudgetExceeded)
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldEqual, ErrBudgetExceeded)
+
+		store = newStore(ctx, mstore, sid, 60, nil)
+		So(store.Save(), ShouldEqual, ErrBudgetExceeded)
+	})
+
+	Convey("Test nested operations charged once", t, func() {
+		ctx := WithBudget(context.Background(), time.Minute)
+		outer := charge(ctx)
+		inner := charge(ctx)
+		time.Sleep(20 * time.Millisecond)
+		inner()
+		outer()
+
+		spent := time.Minute - budgetOf(ctx).remaining
+		So(spent, ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+		So(spent, ShouldBeLessThan, 40*time.Millisecond)
+	})
+
+	Convey("Test queries canceled once the budget runs out", t, func() {
+		ctx := WithBudget(context.Background(), 10*time.Millisecond)
+		release := charge(ctx)
+		store := mstore.bind(ctx)
+		So(store, ShouldNotEqual, mstore)
+		So(store.unbind(), ShouldEqual, mstore)
+
+		time.Sleep(20 * time.Millisecond)
+		var count int
+		err := store.scoped().Count(&count).Error
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		release()
+
+		_, err = mstore.Check(ctx, newSid())
+		So(err, ShouldEqual, ErrBudgetExceeded)
+	})
+}
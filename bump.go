@@ -0,0 +1,44 @@
+package gorm
+
+import (
+	"math/rand"
+	"time"
+)
+
+// BumpPolicy Decides whether reading a session extends its expiry,
+// remaining is the time left before it expires and ttl the expiration of the read
+type BumpPolicy interface {
+	ShouldBump(remaining, ttl time.Duration) bool
+}
+
+// BumpPolicyFunc A function implementation of BumpPolicy
+type BumpPolicyFunc func(remaining, ttl time.Duration) bool
+
+// ShouldBump Calls f(remaining, ttl)
+func (f BumpPolicyFunc) ShouldBump(remaining, ttl time.Duration) bool {
+	return f(remaining, ttl)
+}
+
+// BumpWithProbability Returns a policy that extends the expiry on a fraction p of the reads
+func BumpWithProbability(p float64) BumpPolicy {
+	return BumpPolicyFunc(func(_, _ time.Duration) bool {
+		return rand.Float64() < p
+	})
+}
+
+// BumpBelowRemaining Returns a policy that only extends the expiry once less than
+// the fraction ratio of the ttl remains, e.g. 0.5 writes at most twice per ttl
+func BumpBelowRemaining(ratio float64) BumpPolicy {
+	return BumpPolicyFunc(func(remaining, ttl time.Duration) bool {
+		return float64(remaining) < float64(ttl)*ratio
+	})
+}
+
+// shouldBump Reports whether reading item extends its expiry
+func (s *ManagerStore) shouldBump(item *SessionItem, expired int64) bool {
+	if s.bumpPolicy == nil || expired <= 0 {
+		return true
+	}
+	remaining := time.Until(item.ExpiredAt)
+	return s.bumpPolicy.ShouldBump(remaining, time.Duration(expired)*time.Second)
+}
@@ -0,0 +1,52 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBumpPolicy(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_bump", BumpPolicy: BumpBelowRemaining(0.5)}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test throttling the expiry bump of reads", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 100)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		var before SessionItem
+		So(mstore.db.Where("id=?", sid).First(&before).Error, ShouldBeNil)
+
+		_, err = mstore.Update(ctx, sid, 100)
+		So(err, ShouldBeNil)
+		var item SessionItem
+		So(mstore.db.Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		So(item.ExpiredAt.Equal(before.ExpiredAt), ShouldBeTrue)
+
+		// less than half of the ttl remains
+		So(mstore.db.Where("id=?", sid).Update("expired_at", time.Now().Add(10*time.Second)).Error, ShouldBeNil)
+		_, err = mstore.Update(ctx, sid, 100)
+		So(err, ShouldBeNil)
+		So(mstore.db.Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		So(item.ExpiredAt.After(time.Now().Add(90*time.Second)), ShouldBeTrue)
+	})
+
+	Convey("Test the probabilistic bump policy", t, func() {
+		So(BumpWithProbability(0).ShouldBump(time.Minute, time.Hour), ShouldBeFalse)
+		So(BumpWithProbability(1).ShouldBump(time.Minute, time.Hour), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,92 @@
+package gorm
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultNegativeCacheSize = 10000
+)
+
+// negativeCache Remembers sids that were not found in the database,
+// a nil cache is disabled and never remembers anything
+type negativeCache struct {
+	sync.Mutex
+	ttl   time.Duration
+	size  int
+	items map[string]time.Time
+}
+
+func newNegativeCache(ttl time.Duration, size int) *negativeCache {
+	if size <= 0 {
+		size = defaultNegativeCacheSize
+	}
+
+	return &negativeCache{
+		ttl:   ttl,
+		size:  size,
+		items: make(map[string]time.Time),
+	}
+}
+
+func (c *negativeCache) has(sid string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	expiredAt, ok := c.items[sid]
+	if ok && !expiredAt.After(time.Now()) {
+		delete(c.items, sid)
+		return false
+	}
+	return ok
+}
+
+func (c *negativeCache) add(sid string) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if len(c.items) >= c.size {
+		c.purgeLocked()
+		if len(c.items) >= c.size {
+			return
+		}
+	}
+	c.items[sid] = time.Now().Add(c.ttl)
+}
+
+func (c *negativeCache) remove(sid string) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	delete(c.items, sid)
+	c.Unlock()
+}
+
+// purge Drops the entries whose ttl has passed
+func (c *negativeCache) purge() {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	c.purgeLocked()
+	c.Unlock()
+}
+
+func (c *negativeCache) purgeLocked() {
+	now := time.Now()
+	for sid, expiredAt := range c.items {
+		if !expiredAt.After(now) {
+			delete(c.items, sid)
+		}
+	}
+}
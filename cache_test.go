@@ -0,0 +1,52 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNegativeCache(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_negative", NegativeCacheTTL: time.Minute}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test caching of missing sessions", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+		So(mstore.missing.has(sid), ShouldBeTrue)
+
+		store, err := mstore.Create(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		So(mstore.missing.has(sid), ShouldBeFalse)
+
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		exists, err = mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+	})
+
+	Convey("Test the cache forgets entries after the ttl", t, func() {
+		cache := newNegativeCache(time.Millisecond, 1)
+		cache.add("a")
+		cache.add("b")
+		So(cache.has("a"), ShouldBeTrue)
+		So(cache.has("b"), ShouldBeFalse)
+
+		time.Sleep(time.Millisecond * 2)
+		So(cache.has("a"), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,174 @@
+package gorm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrChallengesDisabled Returned by the challenge operations unless Config.EnableChallenges is set
+var ErrChallengesDisabled = errors.New("gorm session: challenges are not enabled")
+
+// challengeItem One-time code of a session, stored in its own table
+// so that attempts are counted by the database across instances
+type challengeItem struct {
+	ID        string    `gorm:"column:id;size:255;primary_key;"`
+	SessionID string    `gorm:"column:session_id;size:255;"`
+	Name      string    `gorm:"column:name;size:255;"`
+	Code      string    `gorm:"column:code;size:64;"`
+	Attempts  int       `gorm:"column:attempts;"`
+	ExpiredAt time.Time `gorm:"column:expired_at;"`
+}
+
+// challengeTable Returns the name of the challenge table of a session table
+func challengeTable(tableName string) string {
+	return tableName + "_challenges"
+}
+
+// challengeIndex Returns the name of the session_id index of the challenge table
+func challengeIndex(tableName string) string {
+	return "idx_" + challengeTable(tableName) + "_session_id"
+}
+
+// challenges Returns the db of the challenge table
+func (s *ManagerStore) challenges() *gorm.DB {
+	return s.db.Table(challengeTable(s.tableName))
+}
+
+// initChallengeTable Creates the challenge table with the index of its sessions,
+// the challenges of earlier versions have no session and only wait for their expiry
+func (s *ManagerStore) initChallengeTable() error {
+	err := s.autoMigrate(s.challenges(), &challengeItem{})
+	if err != nil {
+		return err
+	}
+
+	table := challengeTable(s.tableName)
+	if !s.db.Dialect().HasIndex(table, challengeIndex(s.tableName)) {
+		s.challenges().AddIndex(challengeIndex(s.tableName), "session_id")
+	}
+	return nil
+}
+
+func (s *ManagerStore) cleanChallenges() error {
+	return s.challenges().Where("expired_at<=?", time.Now()).Delete(nil).Error
+}
+
+// hashCode Only an HMAC of a code under Config.ChallengeKey is stored,
+// so short codes cannot be recovered from the table without the key
+func (s *ManagerStore) hashCode(code string) string {
+	mac := hmac.New(sha256.New, s.challengeKey)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// challengeID Returns the primary key of the challenge name of the session row key,
+// a hash of fixed length so that no sid and name can collide with another pair
+func challengeID(key, name string) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + name))
+	return hex.EncodeToString(sum[:])
+}
+
+// challengeID Returns the primary key of the challenge name of the session
+func (s *Store) challengeID(name string) string {
+	return challengeID(s.mstore.key(s.sid), name)
+}
+
+// sessionChallenges Returns the challenges of the session row key
+func (s *ManagerStore) sessionChallenges(key string) *gorm.DB {
+	return s.challenges().Where("session_id=?", key)
+}
+
+// moveChallenges Moves the challenges of the session row oldkey to key, e.g. on Refresh
+func (s *ManagerStore) moveChallenges(oldkey, key string) error {
+	var items []challengeItem
+	err := s.sessionChallenges(oldkey).Select("id, name").Find(&items).Error
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		err := s.challenges().Where("id=?", item.ID).
+			UpdateColumns(map[string]interface{}{"id": challengeID(key, item.Name), "session_id": key}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) checkChallenges() error {
+	if s.mstore.isClosed() {
+		return ErrStoreClosed
+	} else if !s.mstore.challengesEnabled {
+		return ErrChallengesDisabled
+	}
+	return nil
+}
+
+// SetChallenge Stores the one-time code under key, e.g. for 2FA or email verification,
+// replacing a previous code. The code can be verified up to maxAttempts times within ttl.
+// It is written immediately and does not require Save.
+func (s *Store) SetChallenge(key, code string, maxAttempts int, ttl time.Duration) error {
+	if err := s.checkChallenges(); err != nil {
+		return err
+	}
+
+	id := s.challengeID(key)
+	sessionID := s.mstore.key(s.sid)
+	return s.mstore.atomically(s.ctx, func(tx *ManagerStore) error {
+		db := tx.challenges()
+		err := db.Where("id=?", id).Delete(nil).Error
+		if err != nil {
+			return err
+		}
+
+		return db.Create(&challengeItem{
+			ID:        id,
+			SessionID: sessionID,
+			Name:      key,
+			Code:      tx.hashCode(code),
+			Attempts:  maxAttempts,
+			ExpiredAt: time.Now().Add(ttl),
+		}).Error
+	})
+}
+
+// VerifyChallenge Reports whether code matches the one-time code stored under key.
+// Every call uses up one attempt in the database, a matching code is consumed,
+// an expired or exhausted challenge never matches.
+func (s *Store) VerifyChallenge(key, code string) (bool, error) {
+	if err := s.checkChallenges(); err != nil {
+		return false, err
+	}
+
+	id := s.challengeID(key)
+	db := s.mstore.challenges()
+	result := db.Where("id=? AND attempts>0 AND expired_at>?", id, time.Now()).
+		UpdateColumn("attempts", gorm.Expr("attempts-1"))
+	if err := result.Error; err != nil || result.RowsAffected == 0 {
+		return false, err
+	}
+
+	var item challengeItem
+	err := db.Where("id=?", id).First(&item).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(item.Code), []byte(s.mstore.hashCode(code))) != 1 {
+		return false, nil
+	}
+
+	// only the caller that removes the challenge consumes it
+	result = db.Where("id=?", id).Delete(nil)
+	return result.RowsAffected > 0, result.Error
+}
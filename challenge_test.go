@@ -0,0 +1,140 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChallenge(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_challenge", EnableChallenges: true, ChallengeKey: []byte("challenge")}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test one-time codes with attempt counting", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		gstore := store.(*Store)
+
+		ok, err := gstore.VerifyChallenge("email", "123456")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		So(gstore.SetChallenge("email", "123456", 2, time.Minute), ShouldBeNil)
+
+		// the attempts are counted in the database, not by the session instance
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		ok, err = store.(*Store).VerifyChallenge("email", "000000")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		ok, err = gstore.VerifyChallenge("email", "123456")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		ok, err = gstore.VerifyChallenge("email", "123456")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		So(gstore.SetChallenge("totp", "654321", 1, time.Minute), ShouldBeNil)
+		ok, err = gstore.VerifyChallenge("totp", "000000")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+		ok, err = gstore.VerifyChallenge("totp", "654321")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		So(gstore.SetChallenge("expired", "111111", 3, -time.Second), ShouldBeNil)
+		ok, err = gstore.VerifyChallenge("expired", "111111")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		mstore.clean()
+		var count int
+		So(mstore.challenges().Where("id=?", gstore.challengeID("expired")).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+
+	Convey("Test challenges follow the session", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(store.(*Store).SetChallenge("email", "123456", 3, time.Minute), ShouldBeNil)
+
+		var item challengeItem
+		So(mstore.challenges().Where("id=?", store.(*Store).challengeID("email")).First(&item).Error, ShouldBeNil)
+		So(item.Code, ShouldEqual, mstore.hashCode("123456"))
+		So(item.Code, ShouldNotEqual, (&ManagerStore{challengeKey: []byte("other")}).hashCode("123456"))
+
+		newsid := newSid()
+		store, err = mstore.Refresh(ctx, sid, newsid, 60)
+		So(err, ShouldBeNil)
+		ok, err := store.(*Store).VerifyChallenge("email", "123456")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		So(store.(*Store).SetChallenge("sms", "654321", 3, time.Minute), ShouldBeNil)
+
+		So(mstore.Delete(ctx, newsid), ShouldBeNil)
+		var count int
+		So(mstore.sessionChallenges(mstore.key(newsid)).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+		So(mstore.sessionChallenges(mstore.key(sid)).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+
+	Convey("Test challenges are matched to their session exactly", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		other := sid + ":email"
+		defer mstore.Delete(ctx, other)
+		for _, id := range []string{sid, other} {
+			store, err := mstore.Create(ctx, id, 60)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+		}
+
+		store, err := mstore.Update(ctx, other, 60)
+		So(err, ShouldBeNil)
+		So(store.(*Store).SetChallenge("email", "123456", 3, time.Minute), ShouldBeNil)
+		So(len(store.(*Store).challengeID("email")), ShouldBeLessThanOrEqualTo, 255)
+
+		// deleting the session whose sid prefixes the other keeps the challenges of the other
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		var count int
+		So(mstore.sessionChallenges(mstore.key(other)).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+		ok, err := store.(*Store).VerifyChallenge("email", "123456")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("Test challenges require a key", t, func() {
+		_, err := NewManagerStore(Config{TableName: "session_challenge", EnableChallenges: true}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test challenges are disabled by default", t, func() {
+		mstore := MustManagerStore(Config{TableName: "session_challenge_off"}, "sqlite3", dsn)
+		defer mstore.Close()
+
+		store, err := mstore.Create(context.Background(), newSid(), 60)
+		So(err, ShouldBeNil)
+		So(store.(*Store).SetChallenge("email", "123456", 3, time.Minute), ShouldEqual, ErrChallengesDisabled)
+	})
+}
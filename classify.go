@@ -0,0 +1,136 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrorClass A stable category of a database error, e.g. a metrics label
+type ErrorClass string
+
+// The classes of the database errors
+const (
+	ErrorClassTimeout       ErrorClass = "timeout"
+	ErrorClassConnRefused   ErrorClass = "conn_refused"
+	ErrorClassDuplicate     ErrorClass = "duplicate"
+	ErrorClassSerialization ErrorClass = "serialization"
+	ErrorClassUnknown       ErrorClass = "unknown"
+)
+
+// The errors matched by errors.Is for a DBError of their class
+var (
+	ErrTimeout       = errors.New("gorm session: database timeout")
+	ErrConnRefused   = errors.New("gorm session: database unreachable")
+	ErrDuplicate     = errors.New("gorm session: duplicate key")
+	ErrSerialization = errors.New("gorm session: serialization failure")
+)
+
+var classErrors = map[ErrorClass]error{
+	ErrorClassTimeout:       ErrTimeout,
+	ErrorClassConnRefused:   ErrConnRefused,
+	ErrorClassDuplicate:     ErrDuplicate,
+	ErrorClassSerialization: ErrSerialization,
+}
+
+// classMessages The driver messages of the classes of MySQL, Postgres, SQLite and SQL Server,
+// in the order they are matched
+var classMessages = []struct {
+	class    ErrorClass
+	messages []string
+}{
+	{ErrorClassTimeout, []string{"i/o timeout", "lock wait timeout", "statement timeout", "timeout expired"}},
+	{ErrorClassConnRefused, []string{"connection refused", "bad connection", "broken pipe", "connection reset",
+		"no such host", "database is closed", "unable to open database", "too many connections"}},
+	{ErrorClassDuplicate, []string{"duplicate entry", "duplicate key", "unique constraint",
+		"violation of primary key constraint"}},
+	{ErrorClassSerialization, []string{"deadlock", "could not serialize access", "database is locked",
+		"database table is locked"}},
+}
+
+// DBError A database error of an operation, classified by ClassifyError
+type DBError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// Is Matches the error of the class, e.g. errors.Is(err, ErrTimeout)
+func (e *DBError) Is(target error) bool {
+	return target != nil && classErrors[e.Class] == target
+}
+
+// ClassifyError Returns the class of a database error without parsing it in every consumer,
+// ErrorClassUnknown if it has none and the empty class for nil
+func ClassifyError(err error) ErrorClass {
+	var dbErr *DBError
+	var netErr net.Error
+	var state interface{ SQLState() string }
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &dbErr):
+		return dbErr.Class
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return ErrorClassConnRefused
+	case errors.As(err, &state):
+		if class := sqlStateClass(state.SQLState()); class != ErrorClassUnknown {
+			return class
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, c := range classMessages {
+		for _, m := range c.messages {
+			if strings.Contains(msg, m) {
+				return c.class
+			}
+		}
+	}
+	return ErrorClassUnknown
+}
+
+// sqlStateClass Returns the class of an SQLSTATE code
+func sqlStateClass(code string) ErrorClass {
+	switch {
+	case code == "23505":
+		return ErrorClassDuplicate
+	case code == "40001" || code == "40P01":
+		return ErrorClassSerialization
+	case code == "57014":
+		return ErrorClassTimeout
+	case strings.HasPrefix(code, "08"):
+		return ErrorClassConnRefused
+	}
+	return ErrorClassUnknown
+}
+
+// classify Wraps a classified error in a DBError, other errors are returned as they are
+func classify(err error) error {
+	var dbErr *DBError
+	if err == nil || errors.As(err, &dbErr) {
+		return err
+	}
+
+	class := ClassifyError(err)
+	if class == ErrorClassUnknown {
+		return err
+	}
+	return &DBError{Class: class, Err: err}
+}
@@ -0,0 +1,63 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestClassifyError(t *testing.T) {
+	Convey("Test classifying driver errors", t, func() {
+		So(ClassifyError(nil), ShouldEqual, "")
+		So(ClassifyError(errors.New("foo")), ShouldEqual, ErrorClassUnknown)
+		So(ClassifyError(fmt.Errorf("query: %w", context.DeadlineExceeded)), ShouldEqual, ErrorClassTimeout)
+		So(ClassifyError(errors.New("Error 1205: Lock wait timeout exceeded; try restarting transaction")), ShouldEqual, ErrorClassTimeout)
+		So(ClassifyError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), ShouldEqual, ErrorClassConnRefused)
+		So(ClassifyError(errors.New("Error 1062: Duplicate entry 'foo' for key 'PRIMARY'")), ShouldEqual, ErrorClassDuplicate)
+		So(ClassifyError(errors.New("pq: duplicate key value violates unique constraint")), ShouldEqual, ErrorClassDuplicate)
+		So(ClassifyError(errors.New("Error 1213: Deadlock found when trying to get lock")), ShouldEqual, ErrorClassSerialization)
+		So(ClassifyError(sqlStateError("40001")), ShouldEqual, ErrorClassSerialization)
+		So(ClassifyError(sqlStateError("08006")), ShouldEqual, ErrorClassConnRefused)
+
+		err := classify(errors.New("database is locked"))
+		So(errors.Is(err, ErrSerialization), ShouldBeTrue)
+		So(errors.Is(err, ErrTimeout), ShouldBeFalse)
+		var dbErr *DBError
+		So(errors.As(err, &dbErr), ShouldBeTrue)
+		So(dbErr.Class, ShouldEqual, ErrorClassSerialization)
+		So(classify(err), ShouldEqual, err)
+
+		unknown := errors.New("foo")
+		So(classify(unknown), ShouldEqual, unknown)
+	})
+
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_classify"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test the errors of the operations are classified", t, func() {
+		item := &SessionItem{ID: newSid(), ExpiredAt: time.Now().Add(time.Minute)}
+		So(mstore.db.Create(item).Error, ShouldBeNil)
+		So(ClassifyError(mstore.db.Create(item).Error), ShouldEqual, ErrorClassDuplicate)
+
+		So(mstore.db.DB().Close(), ShouldBeNil)
+		err := mstore.Delete(context.Background(), item.ID)
+		So(errors.Is(err, ErrConnRefused), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,128 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// The stages of serializing a session value counted by CodecErrors
+const (
+	codecMarshal    = "marshal"
+	codecDecrypt    = "decrypt"
+	codecDecompress = "decompress"
+	codecUnmarshal  = "unmarshal"
+)
+
+// quarantineItem An undecodable row moved out of the session table
+type quarantineItem struct {
+	ID            string    `gorm:"column:id;size:255;primary_key;"`
+	Value         string    `gorm:"column:value;size:2048;"`
+	Stage         string    `gorm:"column:stage;size:16;"`
+	Error         string    `gorm:"column:error;size:1024;"`
+	QuarantinedAt time.Time `gorm:"column:quarantined_at;"`
+}
+
+// quarantineTable Returns the name of the quarantine table of a session table
+func quarantineTable(tableName string) string {
+	return tableName + "_quarantine"
+}
+
+// quarantine Returns the db of the quarantine table
+func (s *ManagerStore) quarantine() *gorm.DB {
+	return s.db.Table(quarantineTable(s.tableName))
+}
+
+// CodecErrors Returns the number of values that failed to serialize or deserialize
+// since the store started, by the stage that failed: marshal, decrypt, decompress, unmarshal
+func (s *ManagerStore) CodecErrors() map[string]int64 {
+	root := s.root()
+	root.codecMu.Lock()
+	defer root.codecMu.Unlock()
+
+	counts := make(map[string]int64, len(root.codecErrors))
+	for stage, n := range root.codecErrors {
+		counts[stage] = n
+	}
+	return counts
+}
+
+// decodeValues Returns the values of a stored value and the stage that failed
+func (s *ManagerStore) decodeValues(value string) (map[string]interface{}, string, error) {
+	var values map[string]interface{}
+	if len(value) == 0 {
+		return values, "", nil
+	}
+
+	value, err := s.decryptValue(value)
+	if err != nil {
+		return nil, codecDecrypt, err
+	}
+
+	buf, err := s.decompress(value)
+	if err != nil {
+		return nil, codecDecompress, err
+	}
+
+	err = jsonUnmarshal(buf, &values)
+	if err != nil {
+		return nil, codecUnmarshal, err
+	}
+	return values, "", nil
+}
+
+// parseRow Parses the stored value of the row key, a failure is counted, logged
+// without the value and, with Config.QuarantineUndecodable, the row is moved
+// to the quarantine table
+func (s *ManagerStore) parseRow(key, value string) (map[string]interface{}, error) {
+	values, stage, err := s.decodeValues(value)
+	if err == nil {
+		return values, nil
+	}
+
+	s.codecFailed(stage, s.sessionID(key), err)
+	if s.quarantineEnabled {
+		if qerr := s.quarantineRow(key, value, stage, err); qerr != nil {
+			s.errorf(qerr.Error())
+		}
+	}
+	return nil, err
+}
+
+// codecFailed Counts and logs a value of sid that failed at stage
+func (s *ManagerStore) codecFailed(stage, sid string, err error) {
+	root := s.root()
+	root.codecMu.Lock()
+	if root.codecErrors == nil {
+		root.codecErrors = make(map[string]int64)
+	}
+	root.codecErrors[stage]++
+	root.codecMu.Unlock()
+
+	if logger := s.internalLogger(); logger != nil {
+		logger.ErrorContext(context.Background(), "gorm session: value codec failed",
+			"op", stage, "sid_hash", sidHash(sid), "error", err)
+	}
+}
+
+// quarantineRow Moves the undecodable row key to the quarantine table
+func (s *ManagerStore) quarantineRow(key, value, stage string, cause error) error {
+	msg := cause.Error()
+	if len(msg) > 1024 {
+		msg = msg[:1024]
+	}
+
+	err := s.quarantine().Save(&quarantineItem{
+		ID:            key,
+		Value:         value,
+		Stage:         stage,
+		Error:         msg,
+		QuarantinedAt: time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	_, err = s.deleteIDs([]string{key})
+	return err
+}
@@ -0,0 +1,67 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCodecErrors(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	stdout := new(bytes.Buffer)
+	mstore, err := newManagerStore(db, Config{TableName: "session_codec", QuarantineUndecodable: true, NoBackground: true}, stdout)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test counting, logging and quarantining undecodable values", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("callback", func() {})
+		So(store.Save(), ShouldNotBeNil)
+		So(mstore.CodecErrors()[codecMarshal], ShouldEqual, 1)
+
+		store.Delete("callback")
+		store.Set("user", "alice")
+		So(store.Save(), ShouldBeNil)
+		So(mstore.db.Where("id=?", sid).Update("value", `{"user":`).Error, ShouldBeNil)
+
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldNotBeNil)
+		So(mstore.CodecErrors()[codecUnmarshal], ShouldEqual, 1)
+
+		// the log names the session by its hash and leaves out the value
+		So(stdout.String(), ShouldContainSubstring, sidHash(sid))
+		So(stdout.String(), ShouldNotContainSubstring, sid)
+		So(strings.Contains(stdout.String(), `{"user":`), ShouldBeFalse)
+
+		var item quarantineItem
+		So(mstore.quarantine().Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		So(item.Value, ShouldEqual, `{"user":`)
+		So(item.Stage, ShouldEqual, codecUnmarshal)
+
+		exists, err := mstore.exists(mstore.db, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		status, err := mstore.Status(ctx)
+		So(err, ShouldBeNil)
+		So(status.Codec[codecUnmarshal], ShouldEqual, 1)
+	})
+}
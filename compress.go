@@ -0,0 +1,132 @@
+package gorm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// GzipCompression The tag of the built-in gzip compressor
+	GzipCompression byte = 1
+
+	// compressedMarker Starts a compressed value, followed by the tag
+	// in two hex digits and the base64 encoded payload
+	compressedMarker = '~'
+)
+
+// Compressor A compression algorithm for stored session values
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[byte]Compressor{
+		GzipCompression: gzipCompressor{},
+	}
+)
+
+// RegisterCompressor Registers a compressor under a one-byte tag (e.g. zstd, lz4),
+// the tag is stored with every value it compresses, so it must never be reused
+// for another algorithm. Tag 0 is reserved for uncompressed values.
+func RegisterCompressor(tag byte, c Compressor) {
+	if tag == 0 {
+		panic("gorm session: compressor tag 0 is reserved")
+	}
+
+	compressorsMu.Lock()
+	compressors[tag] = c
+	compressorsMu.Unlock()
+}
+
+func lookupCompressor(tag byte) (Compressor, bool) {
+	compressorsMu.RLock()
+	c, ok := compressors[tag]
+	compressorsMu.RUnlock()
+	return c, ok
+}
+
+// encodeValue Compresses a serialized value with the trained dictionary or the configured
+// compressor unless it is below the threshold, then encrypts it with the configured encryption key
+func (s *ManagerStore) encodeValue(value string) (string, error) {
+	if value == "" {
+		return s.emptyValue(), nil
+	} else if len(value) < s.compressAbove || s.deferred(featureCompression) {
+		return s.encryptValue(value)
+	} else if id, dict := s.currentDictionary(); id != "" {
+		value, err := compressDictionary(value, id, dict)
+		if err != nil {
+			return "", err
+		}
+		return s.encryptValue(value)
+	} else if s.compression == 0 {
+		return s.encryptValue(value)
+	}
+
+	c, ok := lookupCompressor(s.compression)
+	if !ok {
+		return "", fmt.Errorf("gorm session: unknown compressor %d", s.compression)
+	}
+
+	buf, err := c.Compress([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return s.encryptValue(string(compressedMarker) + hex.EncodeToString([]byte{s.compression}) +
+		base64.StdEncoding.EncodeToString(buf))
+}
+
+// decodeValue Returns the serialized value of a stored value,
+// decompressing it with the compressor it was written with
+func decodeValue(value string) ([]byte, error) {
+	if len(value) == 0 || value[0] != compressedMarker {
+		return []byte(value), nil
+	} else if len(value) < 3 {
+		return nil, fmt.Errorf("gorm session: malformed compressed value")
+	}
+
+	tag, err := hex.DecodeString(value[1:3])
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := lookupCompressor(tag[0])
+	if !ok {
+		return nil, fmt.Errorf("gorm session: unknown compressor %d", tag[0])
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(value[3:])
+	if err != nil {
+		return nil, err
+	}
+	return c.Decompress(buf)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,88 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type reverseCompressor struct{}
+
+func (reverseCompressor) Compress(data []byte) ([]byte, error) {
+	buf := make([]byte, len(data))
+	for i, b := range data {
+		buf[len(data)-1-i] = b
+	}
+	return buf, nil
+}
+
+func (c reverseCompressor) Decompress(data []byte) ([]byte, error) {
+	return c.Compress(data)
+}
+
+func TestCompression(t *testing.T) {
+	RegisterCompressor(200, reverseCompressor{})
+
+	dsn := os.TempDir() + "/gorm.db"
+	legacy, err := NewManagerStore(Config{TableName: "session_compressed"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer legacy.Close()
+
+	mstore, err := NewManagerStore(Config{TableName: "session_compressed", Compression: GzipCompression}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	reversed, err := NewManagerStore(Config{TableName: "session_compressed", Compression: 200}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer reversed.Close()
+
+	Convey("Test values are compressed and old rows remain readable", t, func() {
+		ctx := context.Background()
+		large := strings.Repeat("bar", 1000)
+		for _, writer := range []*ManagerStore{legacy, mstore, reversed} {
+			sid := newSid()
+			store, err := writer.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("foo", large)
+			So(store.Save(), ShouldBeNil)
+
+			store, err = mstore.Update(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			foo, ok := store.Get("foo")
+			So(ok, ShouldBeTrue)
+			So(foo, ShouldEqual, large)
+			So(mstore.Delete(ctx, sid), ShouldBeNil)
+		}
+
+		value, err := mstore.encodeValue(`{"foo":"` + large + `"}`)
+		So(err, ShouldBeNil)
+		So(value, ShouldStartWith, "~01")
+		So(len(value), ShouldBeLessThan, len(large))
+
+		buf, err := decodeValue(value)
+		So(err, ShouldBeNil)
+		So(bytes.Contains(buf, []byte(large)), ShouldBeTrue)
+
+		mstore.compressAbove = 100
+		value, err = mstore.encodeValue(`{"foo":"bar"}`)
+		mstore.compressAbove = 0
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, `{"foo":"bar"}`)
+
+		_, err = NewManagerStore(Config{TableName: "session_compressed", Compression: 201}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+	})
+}
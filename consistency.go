@@ -0,0 +1,37 @@
+package gorm
+
+import (
+	"context"
+)
+
+// Consistency The read consistency an operation demands of the store
+type Consistency int
+
+const (
+	// ConsistencyEventual Answers a read of a session another instance created recently
+	// from the negative cache, which may still hold it as missing (default)
+	ConsistencyEventual Consistency = iota
+	// ConsistencyStrong Reads the database, bypassing the negative cache
+	ConsistencyStrong
+)
+
+type consistencyKey struct{}
+
+// WithConsistency Returns a context whose store operations read with the consistency c,
+// e.g. ConsistencyStrong for a checkout or an admin action. It only matters with
+// Config.NegativeCacheTTL, the cache is still updated by the strong operations
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, c)
+}
+
+// consistencyOf Returns the consistency selected by ctx
+func consistencyOf(ctx context.Context) Consistency {
+	c, _ := ctx.Value(consistencyKey{}).(Consistency)
+	return c
+}
+
+// cachedMissing Reports whether the negative cache holds key as missing,
+// never for an operation with ConsistencyStrong
+func (s *ManagerStore) cachedMissing(key string) bool {
+	return !s.strong && s.missing.has(key)
+}
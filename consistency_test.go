@@ -0,0 +1,66 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConsistency(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	cfg := Config{TableName: "session_consistency", NegativeCacheTTL: time.Minute, NoBackground: true}
+	first, err := NewManagerStore(cfg, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer first.Close()
+	second, err := NewManagerStore(cfg, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer second.Close()
+
+	Convey("Test strong reads bypass the negative cache of another instance", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer first.Delete(ctx, sid)
+
+		exists, err := first.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		store, err := second.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("user", "alice")
+		So(store.Save(), ShouldBeNil)
+
+		// the first instance still holds the session as missing
+		exists, err = first.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		strong := WithConsistency(ctx, ConsistencyStrong)
+		exists, err = first.Check(strong, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+		checked, err := first.CheckMany(strong, []string{sid})
+		So(err, ShouldBeNil)
+		So(checked[sid], ShouldBeTrue)
+
+		store, err = first.Update(strong, sid, 60)
+		So(err, ShouldBeNil)
+		user, _ := store.Get("user")
+		So(user, ShouldEqual, "alice")
+
+		// saving through a strong store still clears the cached entry
+		So(store.Save(), ShouldBeNil)
+		exists, err = first.Check(WithConsistency(ctx, ConsistencyEventual), sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+	})
+}
@@ -0,0 +1,41 @@
+package gorm
+
+import "context"
+
+// copyValues Returns a deep copy of session values
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	dup := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		dup[k] = copyValue(v)
+	}
+	return dup
+}
+
+// copyValue Returns a deep copy of the maps and slices decoded from JSON,
+// other values are returned as they are
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyValues(v)
+	case []interface{}:
+		dup := make([]interface{}, len(v))
+		for i, e := range v {
+			dup[i] = copyValue(e)
+		}
+		return dup
+	}
+	return v
+}
+
+// initialValues Returns a copy of the default values of a new session,
+// so sessions never share the map DefaultValues returns
+func (s *ManagerStore) initialValues(ctx context.Context) map[string]interface{} {
+	if s.defaultValues == nil {
+		return nil
+	}
+	return copyValues(s.defaultValues(ctx))
+}
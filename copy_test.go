@@ -0,0 +1,56 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValuesAliasing(t *testing.T) {
+	defaults := map[string]interface{}{
+		"prefs": map[string]interface{}{"theme": "light"},
+		"tags":  []interface{}{"new"},
+	}
+
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName: "session_aliasing",
+		DefaultValues: func(ctx context.Context) map[string]interface{} {
+			return defaults
+		},
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test sessions do not share their values", t, func() {
+		ctx := context.Background()
+		first, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		prefs, _ := first.Get("prefs")
+		prefs.(map[string]interface{})["theme"] = "dark"
+		tags, _ := first.Get("tags")
+		tags.([]interface{})[0] = "old"
+
+		second, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		prefs, _ = second.Get("prefs")
+		So(prefs.(map[string]interface{})["theme"], ShouldEqual, "light")
+		tags, _ = second.Get("tags")
+		So(tags.([]interface{})[0], ShouldEqual, "new")
+		So(defaults["prefs"].(map[string]interface{})["theme"], ShouldEqual, "light")
+
+		err = second.(*Store).Apply(func(values map[string]interface{}) error {
+			values["prefs"].(map[string]interface{})["theme"] = "dark"
+			return errors.New("rejected")
+		})
+		So(err, ShouldNotBeNil)
+		prefs, _ = second.Get("prefs")
+		So(prefs.(map[string]interface{})["theme"], ShouldEqual, "light")
+	})
+}
@@ -0,0 +1,40 @@
+package gorm
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+const (
+	csrfTokenKey  = "_csrf_token"
+	csrfTokenSize = 32
+)
+
+// IssueCSRFToken Generates a new CSRF token and stores it in the session,
+// replacing any previous token, the session must be saved afterwards
+func (s *Store) IssueCSRFToken() (string, error) {
+	buf := make([]byte, csrfTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	s.Set(csrfTokenKey, token)
+	return token, nil
+}
+
+// ValidateCSRFToken Reports whether token matches the CSRF token of the session,
+// comparing in constant time
+func (s *Store) ValidateCSRFToken(token string) bool {
+	v, ok := s.Get(csrfTokenKey)
+	if !ok {
+		return false
+	}
+
+	expected, ok := v.(string)
+	if !ok || expected == "" || len(token) != len(expected) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
@@ -0,0 +1,48 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCSRFToken(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_csrf"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test issuing and validating CSRF tokens", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		gstore := store.(*Store)
+		So(gstore.ValidateCSRFToken(""), ShouldBeFalse)
+
+		token, err := gstore.IssueCSRFToken()
+		So(err, ShouldBeNil)
+		So(token, ShouldNotBeEmpty)
+		So(gstore.ValidateCSRFToken(token), ShouldBeTrue)
+		So(gstore.ValidateCSRFToken(token+"x"), ShouldBeFalse)
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		gstore = store.(*Store)
+		So(gstore.ValidateCSRFToken(token), ShouldBeTrue)
+
+		rotated, err := gstore.IssueCSRFToken()
+		So(err, ShouldBeNil)
+		So(rotated, ShouldNotEqual, token)
+		So(gstore.ValidateCSRFToken(token), ShouldBeFalse)
+		So(gstore.ValidateCSRFToken(rotated), ShouldBeTrue)
+	})
+}
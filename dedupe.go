@@ -0,0 +1,115 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Dedupe Removes duplicate rows of the same sid, which a table without a primary key
+// may get from legacy migrations, keeping the row with the latest expiry of each sid.
+// It returns the number of removed rows, EnforceUniqueIDs prevents new duplicates afterwards.
+func (s *ManagerStore) Dedupe(ctx context.Context) (int64, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []string
+	err = s.scoped().Group("id").Having("COUNT(*)>1").Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, id := range ids {
+		n, err := s.dedupeID(ctx, id)
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// dedupeID Replaces the rows of id with a copy of the one with the latest expiry,
+// the rows cannot be told apart otherwise
+func (s *ManagerStore) dedupeID(ctx context.Context, id string) (int64, error) {
+	tx := s.db.BeginTx(ctx, nil)
+	if err := tx.Error; err != nil {
+		return 0, err
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	rows, err := tx.Where("id=?", id).Order("expired_at DESC").Limit(1).Rows()
+	if err != nil {
+		return 0, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return 0, err
+	}
+
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if !rows.Next() {
+		rows.Close()
+		return 0, rows.Err()
+	}
+	err = rows.Scan(dest...)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	result := tx.Where("id=?", id).Delete(nil)
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+
+	scope := tx.NewScope(nil)
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = scope.Quote(column)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	err = tx.Exec(fmt.Sprintf("INSERT INTO %v (%s) VALUES (%s)",
+		scope.QuotedTableName(), strings.Join(quoted, ","), placeholders), values...).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected - 1, nil
+}
+
+// EnforceUniqueIDs Adds a unique index on the id column, for tables
+// created without a primary key, the duplicates must be removed by Dedupe first
+func (s *ManagerStore) EnforceUniqueIDs(ctx context.Context) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	index := "idx_" + s.tableName + "_id"
+	if s.db.Dialect().HasIndex(s.tableName, index) {
+		return nil
+	}
+	return s.db.AddUniqueIndex(index, "id").Error
+}
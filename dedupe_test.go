@@ -0,0 +1,59 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDedupe(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// a legacy table without a primary key
+	db.DropTableIfExists("session_legacy")
+	err = db.Exec("CREATE TABLE session_legacy (id varchar(255), value varchar(2048), created_at datetime, expired_at datetime)").Error
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	mstore, err := NewStoreWithConfig(db, Config{TableName: "session_legacy"})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test removing duplicate rows of a sid", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		now := time.Now()
+		for i, value := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+			err := mstore.db.Create(&SessionItem{ID: sid, Value: value, CreatedAt: now, ExpiredAt: now.Add(time.Duration(i+1) * time.Minute)}).Error
+			So(err, ShouldBeNil)
+		}
+		err := mstore.db.Create(&SessionItem{ID: newSid(), Value: `{"n":4}`, CreatedAt: now, ExpiredAt: now.Add(time.Minute)}).Error
+		So(err, ShouldBeNil)
+
+		removed, err := mstore.Dedupe(ctx)
+		So(err, ShouldBeNil)
+		So(removed, ShouldEqual, 2)
+
+		store, err := mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		n, _ := store.Get("n")
+		So(n, ShouldEqual, 3)
+
+		So(mstore.EnforceUniqueIDs(ctx), ShouldBeNil)
+		err = mstore.db.Create(&SessionItem{ID: sid, CreatedAt: now, ExpiredAt: now}).Error
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,279 @@
+package gorm
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// dictionaryMarker Starts a value compressed with a trained dictionary, followed
+	// by the id of the dictionary and the base64 encoded deflate payload
+	dictionaryMarker = '^'
+
+	// dictionaryIDSize The length of a dictionary id in hex digits
+	dictionaryIDSize = 8
+
+	// maxDictionarySize The deflate window, bytes further back are never referenced
+	maxDictionarySize = 32 << 10
+)
+
+// ErrDictionaryDisabled Returned by TrainDictionary unless Config.CompressionDictionary is set
+var ErrDictionaryDisabled = errors.New("gorm session: dictionary compression is not enabled")
+
+// dictionaryItem A dictionary trained on the values of a session table
+type dictionaryItem struct {
+	ID        string    `gorm:"column:id;size:16;primary_key;"`
+	Dict      string    `gorm:"column:dict;size:65536;"`
+	CreatedAt time.Time `gorm:"column:created_at;"`
+}
+
+// dictionaryTable Returns the name of the dictionary table of a session table
+func dictionaryTable(tableName string) string {
+	return tableName + "_dicts"
+}
+
+// dictionaries Returns the db of the dictionary table
+func (s *ManagerStore) dictionaries() *gorm.DB {
+	return s.db.Table(dictionaryTable(s.tableName))
+}
+
+// TrainDictionary Builds a compression dictionary of the key/value pairs that recur across up to
+// samples live sessions picked at random, stores it and compresses the values written from now on
+// with it, other instances pick it up on their next GC run. It returns the id of the dictionary,
+// that is stored with every value it compresses, so the values of earlier dictionaries remain readable.
+func (s *ManagerStore) TrainDictionary(ctx context.Context, samples int) (string, error) {
+	if s.isClosed() {
+		return "", ErrStoreClosed
+	} else if !s.dictEnabled {
+		return "", ErrDictionaryDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var items []*SessionItem
+	err = s.scoped().Where("expired_at>?", time.Now()).
+		Order(s.randomOrder()).Limit(samples).Find(&items).Error
+	if err != nil {
+		return "", err
+	}
+	if s.separateValues && len(items) > 0 {
+		if err := s.loadValues(items); err != nil {
+			return "", err
+		}
+	}
+
+	var sampled []map[string]interface{}
+	for _, item := range items {
+		values, _, err := s.decodeValues(item.Value)
+		if err == nil && len(values) > 0 {
+			sampled = append(sampled, values)
+		}
+	}
+
+	dict := trainDictionary(sampled)
+	if len(dict) == 0 {
+		return "", fmt.Errorf("gorm session: no session values to train a dictionary on")
+	}
+
+	sum := sha256.Sum256(dict)
+	item := &dictionaryItem{
+		ID:        hex.EncodeToString(sum[:dictionaryIDSize/2]),
+		Dict:      base64.StdEncoding.EncodeToString(dict),
+		CreatedAt: time.Now(),
+	}
+	err = s.dictionaries().Save(item).Error
+	if err != nil {
+		return "", err
+	}
+
+	s.useDictionary(item.ID, dict)
+	return item.ID, nil
+}
+
+// trainDictionary Returns the serialized key/value pairs and keys that recur across the samples,
+// by the bytes they would save, the most valuable last where deflate references them at the
+// shortest distance
+func trainDictionary(samples []map[string]interface{}) []byte {
+	counts := make(map[string]int)
+	for _, values := range samples {
+		seen := make(map[string]bool)
+		for key, value := range values {
+			k, err := jsonMarshal(key)
+			if err != nil {
+				continue
+			}
+			v, err := jsonMarshal(value)
+			if err != nil {
+				continue
+			}
+
+			for _, segment := range []string{string(k) + ":", string(k) + ":" + string(v)} {
+				if !seen[segment] {
+					seen[segment] = true
+					counts[segment]++
+				}
+			}
+		}
+	}
+
+	minCount := 2
+	if len(samples) < minCount {
+		minCount = len(samples)
+	}
+
+	var segments []string
+	for segment, n := range counts {
+		if n >= minCount {
+			segments = append(segments, segment)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		si, sj := counts[segments[i]]*len(segments[i]), counts[segments[j]]*len(segments[j])
+		if si != sj {
+			return si > sj
+		}
+		return segments[i] < segments[j]
+	})
+
+	size := 0
+	for i, segment := range segments {
+		if size+len(segment) > maxDictionarySize {
+			segments = segments[:i]
+			break
+		}
+		size += len(segment)
+	}
+
+	dict := make([]byte, 0, size)
+	for i := len(segments) - 1; i >= 0; i-- {
+		dict = append(dict, segments[i]...)
+	}
+	return dict
+}
+
+// useDictionary Makes a dictionary the one the values are compressed with
+func (s *ManagerStore) useDictionary(id string, dict []byte) {
+	root := s.root()
+	root.dictMu.Lock()
+	defer root.dictMu.Unlock()
+
+	if root.dicts == nil {
+		root.dicts = make(map[string][]byte)
+	}
+	root.dicts[id] = dict
+	root.dictID = id
+}
+
+// loadDictionary Uses the latest trained dictionary of the table, if any
+func (s *ManagerStore) loadDictionary() error {
+	var items []dictionaryItem
+	err := s.dictionaries().Order("created_at DESC").Limit(1).Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return err
+	}
+
+	dict, err := base64.StdEncoding.DecodeString(items[0].Dict)
+	if err != nil {
+		return err
+	}
+	s.useDictionary(items[0].ID, dict)
+	return nil
+}
+
+// currentDictionary Returns the dictionary the values are compressed with, empty if none
+func (s *ManagerStore) currentDictionary() (string, []byte) {
+	if !s.dictEnabled || s.deferred(featureDictionary) {
+		return "", nil
+	}
+
+	root := s.root()
+	root.dictMu.RLock()
+	defer root.dictMu.RUnlock()
+	return root.dictID, root.dicts[root.dictID]
+}
+
+// dictionary Returns the dictionary id, reading it from the table unless it is cached
+func (s *ManagerStore) dictionary(id string) ([]byte, error) {
+	root := s.root()
+	root.dictMu.RLock()
+	dict, ok := root.dicts[id]
+	root.dictMu.RUnlock()
+	if ok {
+		return dict, nil
+	}
+
+	var items []dictionaryItem
+	err := s.dictionaries().Where("id=?", id).Limit(1).Find(&items).Error
+	if err != nil {
+		return nil, err
+	} else if len(items) == 0 {
+		return nil, fmt.Errorf("gorm session: unknown compression dictionary %s", id)
+	}
+
+	dict, err = base64.StdEncoding.DecodeString(items[0].Dict)
+	if err != nil {
+		return nil, err
+	}
+
+	root.dictMu.Lock()
+	if root.dicts == nil {
+		root.dicts = make(map[string][]byte)
+	}
+	root.dicts[id] = dict
+	root.dictMu.Unlock()
+	return dict, nil
+}
+
+// compressDictionary Compresses a serialized value with the dictionary id
+func compressDictionary(value, id string, dict []byte) (string, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriterDict(&buf, flate.BestCompression, dict)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write([]byte(value)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return string(dictionaryMarker) + id + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompress Returns the serialized value of a decrypted value,
+// decompressing it with the dictionary or the compressor it was written with
+func (s *ManagerStore) decompress(value string) ([]byte, error) {
+	if len(value) == 0 || value[0] != dictionaryMarker {
+		return decodeValue(value)
+	} else if len(value) < 1+dictionaryIDSize {
+		return nil, fmt.Errorf("gorm session: malformed compressed value")
+	}
+
+	dict, err := s.dictionary(value[1 : 1+dictionaryIDSize])
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(value[1+dictionaryIDSize:])
+	if err != nil {
+		return nil, err
+	}
+	r := flate.NewReaderDict(bytes.NewReader(buf), dict)
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
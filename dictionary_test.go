@@ -0,0 +1,82 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompressionDictionary(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	cfg := Config{TableName: "session_dictionary", CompressionDictionary: true, NoBackground: true}
+	mstore, err := NewManagerStore(cfg, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test compressing the values with a trained dictionary", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		So(mstore.dictionaries().Delete(nil).Error, ShouldBeNil)
+		mstore.dictID = ""
+
+		save := func(mstore *ManagerStore, i int) string {
+			sid := newSid()
+			store, err := mstore.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("user_id", fmt.Sprintf("user-%d", i))
+			store.Set("role", "customer")
+			store.Set("locale", "en-US")
+			store.Set("cart_items", []string{"apple", "banana"})
+			So(store.Save(), ShouldBeNil)
+			return sid
+		}
+
+		var sids []string
+		for i := 0; i < 10; i++ {
+			sids = append(sids, save(mstore, i))
+		}
+		plain, err := mstore.getItem(mstore.key(sids[0]))
+		So(err, ShouldBeNil)
+		So(plain.Value[0], ShouldEqual, '{')
+
+		id, err := mstore.TrainDictionary(ctx, 10)
+		So(err, ShouldBeNil)
+		So(id, ShouldHaveLength, dictionaryIDSize)
+
+		sid := save(mstore, 10)
+		item, err := mstore.getItem(mstore.key(sid))
+		So(err, ShouldBeNil)
+		So(item.Value[:1+dictionaryIDSize], ShouldEqual, "^"+id)
+		So(len(item.Value), ShouldBeLessThan, len(plain.Value)/2)
+
+		peer, err := NewManagerStore(cfg, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer peer.Close()
+		store, err := peer.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		userID, _ := store.Get("user_id")
+		So(userID, ShouldEqual, "user-10")
+
+		n, err := peer.MigrateValues(ctx, 0)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 10)
+		store, err = mstore.Update(ctx, sids[0], 60)
+		So(err, ShouldBeNil)
+		userID, _ = store.Get("user_id")
+		So(userID, ShouldEqual, "user-0")
+	})
+
+	Convey("Test training requires the option", t, func() {
+		other, err := NewManagerStore(Config{TableName: "session_dictionary", NoBackground: true}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer other.Close()
+		_, err = other.TrainDictionary(context.Background(), 10)
+		So(err, ShouldEqual, ErrDictionaryDisabled)
+	})
+}
@@ -0,0 +1,30 @@
+package gorm
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// StartDraining Stops extending the expiry of existing sessions,
+// Update and Save no longer bump expired_at so sessions run out naturally
+// (e.g. on the old stack of a blue/green deploy), new sessions still get a full TTL
+func (s *ManagerStore) StartDraining(_ context.Context) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+	atomic.StoreInt32(&s.root().draining, 1)
+	return nil
+}
+
+// StopDraining Resumes extending the expiry of sessions
+func (s *ManagerStore) StopDraining(_ context.Context) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+	atomic.StoreInt32(&s.root().draining, 0)
+	return nil
+}
+
+func (s *ManagerStore) isDraining() bool {
+	return atomic.LoadInt32(&s.root().draining) == 1
+}
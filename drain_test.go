@@ -0,0 +1,50 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDraining(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_drain"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test draining stops extending sessions", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		var before SessionItem
+		So(mstore.db.Where("id=?", sid).First(&before).Error, ShouldBeNil)
+
+		So(mstore.StartDraining(ctx), ShouldBeNil)
+		store, err = mstore.Update(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		store.Set("foo", "baz")
+		So(store.Save(), ShouldBeNil)
+
+		var after SessionItem
+		So(mstore.db.Where("id=?", sid).First(&after).Error, ShouldBeNil)
+		So(after.Value, ShouldContainSubstring, "baz")
+		So(after.ExpiredAt.Equal(before.ExpiredAt), ShouldBeTrue)
+
+		So(mstore.StopDraining(ctx), ShouldBeNil)
+		_, err = mstore.Update(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		So(mstore.db.Where("id=?", sid).First(&after).Error, ShouldBeNil)
+		So(after.ExpiredAt.After(before.ExpiredAt), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,41 @@
+package gorm
+
+// EmptyPolicy How Save treats a session without values
+type EmptyPolicy int
+
+const (
+	// EmptyKeep Writes the empty value, the row stays until it expires
+	EmptyKeep EmptyPolicy = iota
+	// EmptyDelete Deletes the row of the session
+	EmptyDelete
+	// EmptySkip Writes nothing, an existing row keeps its values and expiry
+	EmptySkip
+)
+
+// empty Reports whether the session has no values
+func (s *Store) empty() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.values) == 0
+}
+
+// saveEmpty Applies the empty policy to the session, reports false
+// if the session has values or the policy writes empty sessions
+func (s *Store) saveEmpty(mstore *ManagerStore) (bool, error) {
+	if mstore.emptyPolicy == EmptyKeep || !s.empty() {
+		return false, nil
+	} else if mstore.emptyPolicy == EmptySkip {
+		return true, nil
+	}
+
+	// an emptied session is not revoked, so no tombstone is buried
+	n, err := mstore.deleteSession(mstore.key(s.sid))
+	if err != nil || n == 0 {
+		return true, err
+	}
+	if err := mstore.notifyPeers("delete", s.sid); err != nil {
+		return true, err
+	}
+	mstore.fireDelete(s.sid)
+	return true, nil
+}
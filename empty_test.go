@@ -0,0 +1,70 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEmptyPolicy(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	ctx := context.Background()
+
+	for _, policy := range []EmptyPolicy{EmptyKeep, EmptyDelete, EmptySkip} {
+		mstore, err := NewManagerStore(Config{TableName: "session_empty", EmptyPolicy: policy}, "sqlite3", dsn)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		defer mstore.Close()
+
+		Convey(fmt.Sprintf("Test saving an empty session with policy %d", policy), t, func() {
+			fresh := newSid()
+			defer mstore.Delete(ctx, fresh)
+			store, err := mstore.Create(ctx, fresh, 60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+			exists, err := mstore.exists(mstore.db, fresh)
+			So(err, ShouldBeNil)
+			So(exists, ShouldEqual, policy == EmptyKeep)
+
+			sid := newSid()
+			defer mstore.Delete(ctx, sid)
+			store, err = mstore.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("user", "alice")
+			So(store.Save(), ShouldBeNil)
+
+			store.Delete("user")
+			So(store.Save(), ShouldBeNil)
+			exists, err = mstore.exists(mstore.db, sid)
+			So(err, ShouldBeNil)
+			So(exists, ShouldEqual, policy != EmptyDelete)
+
+			store, err = mstore.Update(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			_, ok := store.Get("user")
+			So(ok, ShouldEqual, policy == EmptySkip)
+
+			// SaveMany applies the policy to every session
+			other := newSid()
+			defer mstore.Delete(ctx, other)
+			empty, err := mstore.Create(ctx, other, 60)
+			So(err, ShouldBeNil)
+			full, err := mstore.Create(ctx, newSid(), 60)
+			So(err, ShouldBeNil)
+			full.Set("user", "bob")
+			So(mstore.SaveMany(ctx, []*Store{empty.(*Store), full.(*Store)}, 0), ShouldBeNil)
+			exists, err = mstore.exists(mstore.db, other)
+			So(err, ShouldBeNil)
+			So(exists, ShouldEqual, policy == EmptyKeep)
+			exists, err = mstore.exists(mstore.db, full.SessionID())
+			So(err, ShouldBeNil)
+			So(exists, ShouldBeTrue)
+			So(mstore.Delete(ctx, full.SessionID()), ShouldBeNil)
+		})
+	}
+}
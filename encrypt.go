@@ -0,0 +1,113 @@
+package gorm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	// encryptedMarker Starts an encrypted value, followed by the format version
+	// in two hex digits, the key id in eight hex digits and the base64 encoded
+	// nonce and ciphertext
+	encryptedMarker = '!'
+
+	encryptionVersion = "01"
+	encryptedHeader   = 1 + len(encryptionVersion) + 8
+)
+
+// ErrNoEncryptionKey Returned when reading a value encrypted with a key the store does not have
+var ErrNoEncryptionKey = errors.New("gorm session: no encryption key for value")
+
+// keyring The AES-GCM keys of a store, the current key encrypts new values
+// and all keys decrypt, selected by the id stored with the value
+type keyring struct {
+	id   string
+	keys map[string]cipher.AEAD
+}
+
+// newKeyring Returns the keyring of the current key and the previous keys,
+// each of them 16, 24 or 32 bytes long for AES-128, AES-192 or AES-256
+func newKeyring(current []byte, previous [][]byte) (*keyring, error) {
+	ring := &keyring{keys: make(map[string]cipher.AEAD)}
+	for i, key := range append([][]byte{current}, previous...) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("gorm session: invalid encryption key: %v", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		id := keyID(key)
+		if i == 0 {
+			ring.id = id
+		}
+		ring.keys[id] = aead
+	}
+	return ring, nil
+}
+
+// keyID Identifies a key by the first four bytes of its SHA-256 hash
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// encryptValue Encrypts an encoded value with the current key,
+// the header is authenticated along with the value
+func (s *ManagerStore) encryptValue(value string) (string, error) {
+	if s.keyring == nil || value == "" || s.deferred(featureEncryption) {
+		return value, nil
+	}
+
+	aead := s.keyring.keys[s.keyring.id]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	header := string(encryptedMarker) + encryptionVersion + s.keyring.id
+	buf := aead.Seal(nonce, nonce, []byte(value), []byte(header))
+	return header + base64.StdEncoding.EncodeToString(buf), nil
+}
+
+// decryptValue Returns the encoded value of a stored value,
+// values that are not encrypted are returned as they are
+func (s *ManagerStore) decryptValue(value string) (string, error) {
+	if len(value) == 0 || value[0] != encryptedMarker {
+		return value, nil
+	} else if len(value) < encryptedHeader {
+		return "", fmt.Errorf("gorm session: malformed encrypted value")
+	} else if version := value[1:3]; version != encryptionVersion {
+		return "", fmt.Errorf("gorm session: unknown encryption version %s", version)
+	}
+
+	if s.keyring == nil {
+		return "", ErrNoEncryptionKey
+	}
+	aead, ok := s.keyring.keys[value[3:encryptedHeader]]
+	if !ok {
+		return "", ErrNoEncryptionKey
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(value[encryptedHeader:])
+	if err != nil {
+		return "", err
+	} else if len(buf) < aead.NonceSize() {
+		return "", fmt.Errorf("gorm session: malformed encrypted value")
+	}
+
+	nonce := buf[:aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, buf[aead.NonceSize():], []byte(value[:encryptedHeader]))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
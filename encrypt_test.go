@@ -0,0 +1,86 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncryption(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 16)
+
+	dsn := os.TempDir() + "/gorm.db"
+	plain, err := NewManagerStore(Config{TableName: "session_encrypted"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer plain.Close()
+
+	old, err := NewManagerStore(Config{TableName: "session_encrypted", EncryptionKey: oldKey, Compression: GzipCompression}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer old.Close()
+
+	mstore, err := NewManagerStore(Config{
+		TableName:      "session_encrypted",
+		EncryptionKey:  newKey,
+		DecryptionKeys: [][]byte{oldKey},
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test values are encrypted and remain readable through a key rotation", t, func() {
+		ctx := context.Background()
+		for _, writer := range []*ManagerStore{plain, old, mstore} {
+			sid := newSid()
+			store, err := writer.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("email", "foo@example.com")
+			So(store.Save(), ShouldBeNil)
+
+			item, err := writer.getItem(writer.key(sid))
+			So(err, ShouldBeNil)
+			So(strings.Contains(item.Value, "foo@example.com"), ShouldEqual, writer == plain)
+
+			store, err = mstore.Update(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			email, ok := store.Get("email")
+			So(ok, ShouldBeTrue)
+			So(email, ShouldEqual, "foo@example.com")
+		}
+
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		_, err = old.Update(ctx, sid, 60)
+		So(err, ShouldEqual, ErrNoEncryptionKey)
+		_, err = plain.Update(ctx, sid, 60)
+		So(err, ShouldEqual, ErrNoEncryptionKey)
+
+		item, err := mstore.getItem(mstore.key(sid))
+		So(err, ShouldBeNil)
+		tampered := item.Value[:len(item.Value)-2] + "AA"
+		if tampered == item.Value {
+			tampered = item.Value[:len(item.Value)-2] + "BB"
+		}
+		_, err = mstore.parseValue(tampered)
+		So(err, ShouldNotBeNil)
+
+		_, err = NewManagerStore(Config{TableName: "session_encrypted", EncryptionKey: []byte("short")}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+	})
+}
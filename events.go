@@ -0,0 +1,122 @@
+package gorm
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// defaultEventBuffer The capacity of a Subscribe channel by default
+const defaultEventBuffer = 64
+
+// EventType The kind of a SessionEvent
+type EventType int
+
+const (
+	// EventExpired A session removed by GC as expired or older than MaxSessionAge
+	EventExpired EventType = iota
+	// EventDeleted A session removed by Delete
+	EventDeleted
+	// EventRefreshed A session moved to a new sid by Refresh, SID is the old sid
+	EventRefreshed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventExpired:
+		return "expired"
+	case EventDeleted:
+		return "deleted"
+	case EventRefreshed:
+		return "refreshed"
+	}
+	return "unknown"
+}
+
+// SessionEvent A session removed from the store
+type SessionEvent struct {
+	Type EventType
+	SID  string
+	Time time.Time
+	Peer bool // Reported by another instance through ListenPeers
+}
+
+// Subscribe Returns a channel of the sessions removed by GC, Delete or Refresh from now on, e.g. to close
+// their websockets. The channel buffers Config.EventBuffer events (default 64), the events of
+// a subscriber whose buffer is full are dropped rather than stalling GC and counted by
+// DroppedEvents. The channel is closed by Unsubscribe or when the store is closed.
+func (s *ManagerStore) Subscribe() <-chan SessionEvent {
+	root := s.root()
+	size := root.eventBuffer
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+	ch := make(chan SessionEvent, size)
+
+	root.subsMu.Lock()
+	defer root.subsMu.Unlock()
+	if root.isClosed() {
+		close(ch)
+		return ch
+	}
+	if root.subs == nil {
+		root.subs = make(map[<-chan SessionEvent]chan SessionEvent)
+	}
+	root.subs[ch] = ch
+	atomic.AddInt32(&root.subscribers, 1)
+	return ch
+}
+
+// Unsubscribe Stops the events of a channel returned by Subscribe and closes it
+func (s *ManagerStore) Unsubscribe(ch <-chan SessionEvent) {
+	root := s.root()
+	root.subsMu.Lock()
+	defer root.subsMu.Unlock()
+
+	if c, ok := root.subs[ch]; ok {
+		delete(root.subs, ch)
+		atomic.AddInt32(&root.subscribers, -1)
+		close(c)
+	}
+}
+
+// DroppedEvents Returns the number of events dropped for subscribers that fell behind
+func (s *ManagerStore) DroppedEvents() int64 {
+	return atomic.LoadInt64(&s.root().droppedEvents)
+}
+
+// subscribed Reports whether a channel is subscribed to the events
+func (s *ManagerStore) subscribed() bool {
+	return atomic.LoadInt32(&s.root().subscribers) > 0
+}
+
+// publish Sends an event of this instance to every subscriber that has room for it
+func (s *ManagerStore) publish(typ EventType, sid string) {
+	s.publishEvent(SessionEvent{Type: typ, SID: sid, Time: time.Now()})
+}
+
+// publishEvent Sends an event to every subscriber that has room for it
+func (s *ManagerStore) publishEvent(event SessionEvent) {
+	root := s.root()
+	root.subsMu.Lock()
+	defer root.subsMu.Unlock()
+
+	for _, ch := range root.subs {
+		select {
+		case ch <- event:
+		default:
+			atomic.AddInt64(&root.droppedEvents, 1)
+		}
+	}
+}
+
+// closeSubscribers Closes the channels of the subscribers
+func (s *ManagerStore) closeSubscribers() {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	for _, ch := range s.subs {
+		close(ch)
+	}
+	s.subs = nil
+	atomic.StoreInt32(&s.subscribers, 0)
+}
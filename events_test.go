@@ -0,0 +1,70 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubscribe(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_events", EventBuffer: 2}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	Convey("Test the events of the removed sessions", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		events := mstore.Subscribe()
+
+		create := func(sid string, expired int64) {
+			store, err := mstore.Create(ctx, sid, expired)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+		}
+
+		sid, expiring := newSid(), newSid()
+		create(sid, 60)
+		create(expiring, 1)
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		time.Sleep(1100 * time.Millisecond)
+		_, err := mstore.clean()
+		So(err, ShouldBeNil)
+
+		event := <-events
+		So(event.Type, ShouldEqual, EventDeleted)
+		So(event.SID, ShouldEqual, sid)
+		event = <-events
+		So(event.Type, ShouldEqual, EventExpired)
+		So(event.SID, ShouldEqual, expiring)
+		So(event.Type.String(), ShouldEqual, "expired")
+
+		for i := 0; i < 3; i++ {
+			sid := newSid()
+			create(sid, 60)
+			So(mstore.Delete(ctx, sid), ShouldBeNil)
+		}
+		So(len(events), ShouldEqual, 2)
+		So(mstore.DroppedEvents(), ShouldEqual, 1)
+
+		mstore.Unsubscribe(events)
+		<-events
+		<-events
+		_, ok := <-events
+		So(ok, ShouldBeFalse)
+		So(mstore.subscribed(), ShouldBeFalse)
+
+		events = mstore.Subscribe()
+		So(mstore.Close(), ShouldBeNil)
+		_, ok = <-events
+		So(ok, ShouldBeFalse)
+		_, ok = <-mstore.Subscribe()
+		So(ok, ShouldBeFalse)
+	})
+}
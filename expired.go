@@ -0,0 +1,60 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-session/session"
+)
+
+// ErrSessionExpired Returned by Update for an expired session with the ExpiredError policy
+var ErrSessionExpired = errors.New("gorm session: session expired")
+
+// ExpiredPolicy How Update treats a session that is expired but not yet removed by GC,
+// sessions beyond MaxSessionAge are always treated as missing
+type ExpiredPolicy int
+
+const (
+	// ExpiredRecreate Returns an empty session and leaves the old row to GC
+	ExpiredRecreate ExpiredPolicy = iota
+	// ExpiredDelete Deletes the old row immediately and returns an empty session
+	ExpiredDelete
+	// ExpiredResurrect Restores the old values with a new expiry
+	ExpiredResurrect
+	// ExpiredError Returns ErrSessionExpired
+	ExpiredError
+)
+
+// updateExpired Applies the expired policy to the expired row of sid
+func (s *ManagerStore) updateExpired(ctx context.Context, sid string, expired int64, item *SessionItem) (session.Store, error) {
+	switch s.expiredPolicy {
+	case ExpiredDelete:
+		n, err := s.deleteSession(item.ID)
+		if err != nil {
+			return nil, err
+		} else if n > 0 {
+			s.fireExpire([]string{item.ID})
+		}
+	case ExpiredResurrect:
+		if item.Value == "" {
+			break
+		}
+
+		// the expiry is extended even while draining, the row would be unreadable otherwise
+		fields := s.expiryFields(expired)
+		fields["expired_at"] = s.GetExpired(expired)
+		result := s.db.Where("id=?", item.ID).Updates(fields)
+		if err := result.Error; err != nil {
+			return nil, err
+		}
+
+		values, err := s.parseRow(item.ID, item.Value)
+		if err != nil {
+			return nil, err
+		}
+		return newStore(ctx, s, sid, expired, values).loaded(item, true), nil
+	case ExpiredError:
+		return nil, ErrSessionExpired
+	}
+	return newStore(ctx, s, sid, expired, nil), nil
+}
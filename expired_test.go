@@ -0,0 +1,163 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpiredPolicy(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+
+	Convey("Test the policies for reading an expired session", t, func() {
+		ctx := context.Background()
+		for _, policy := range []ExpiredPolicy{ExpiredRecreate, ExpiredDelete, ExpiredResurrect, ExpiredError} {
+			mstore, err := NewManagerStore(Config{TableName: "session_expired_policy", ExpiredPolicy: policy}, "sqlite3", dsn)
+			So(err, ShouldBeNil)
+
+			sid := newSid()
+			store, err := mstore.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+			So(mstore.db.Where("id=?", sid).Update("expired_at", time.Now().Add(-time.Second)).Error, ShouldBeNil)
+
+			store, err = mstore.Update(ctx, sid, 60)
+			exists, _ := mstore.exists(mstore.db, sid)
+			switch policy {
+			case ExpiredRecreate:
+				So(err, ShouldBeNil)
+				So(exists, ShouldBeTrue)
+				_, ok := store.Get("foo")
+				So(ok, ShouldBeFalse)
+			case ExpiredDelete:
+				So(err, ShouldBeNil)
+				So(exists, ShouldBeFalse)
+				_, ok := store.Get("foo")
+				So(ok, ShouldBeFalse)
+			case ExpiredResurrect:
+				So(err, ShouldBeNil)
+				foo, ok := store.Get("foo")
+				So(ok, ShouldBeTrue)
+				So(foo, ShouldEqual, "bar")
+
+				ok, err = mstore.Check(ctx, sid)
+				So(err, ShouldBeNil)
+				So(ok, ShouldBeTrue)
+			case ExpiredError:
+				So(err, ShouldEqual, ErrSessionExpired)
+			}
+
+			So(mstore.Delete(ctx, sid), ShouldBeNil)
+			So(mstore.Close(), ShouldBeNil)
+		}
+	})
+}
+
+func TestExpiredDeleteHooks(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	var expired []string
+	mstore, err := NewManagerStore(Config{
+		TableName:      "session_expired_delete",
+		ExpiredPolicy:  ExpiredDelete,
+		SeparateValues: true,
+		OnExpire:       func(sid string) { expired = append(expired, sid) },
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test the ExpiredDelete policy removes the session like GC", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(mstore.db.Where("id=?", sid).Update("expired_at", time.Now().Add(-time.Second)).Error, ShouldBeNil)
+
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(expired, ShouldResemble, []string{sid})
+
+		var count int
+		So(mstore.values().Where("id=?", sid).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+
+	Convey("Test the ExpiredDelete policy reports the session once the transaction commits", t, func() {
+		ctx := context.Background()
+		expired = nil
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(mstore.db.Where("id=?", sid).Update("expired_at", time.Now().Add(-time.Second)).Error, ShouldBeNil)
+
+		errRollback := errors.New("rollback")
+		err = mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			_, err := tx.Update(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			So(expired, ShouldBeEmpty)
+			return errRollback
+		})
+		So(err, ShouldEqual, errRollback)
+		So(expired, ShouldBeEmpty)
+
+		err = mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			_, err := tx.Update(ctx, sid, 60)
+			return err
+		})
+		So(err, ShouldBeNil)
+		So(expired, ShouldResemble, []string{sid})
+	})
+}
+
+func TestCheckExpired(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName:        "session_check_expired",
+		ExpiredPolicy:    ExpiredResurrect,
+		NegativeCacheTTL: time.Minute,
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test Check reports expired sessions as missing", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(mstore.db.Where("id=?", sid).Update("expired_at", time.Now().Add(-time.Second)).Error, ShouldBeNil)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		found, err := mstore.CheckMany(ctx, []string{sid})
+		So(err, ShouldBeNil)
+		So(found[sid], ShouldBeFalse)
+
+		// the expired row is not remembered as missing
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "bar")
+
+		exists, err = mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+	})
+}
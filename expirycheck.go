@@ -0,0 +1,117 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-session/session"
+)
+
+// ExpiryCheck The expiries of a session manager compared by CheckExpiry
+type ExpiryCheck struct {
+	Expired        time.Duration // Row expiry the manager passes to the store (session.SetExpired), 0 never expires
+	CookieLifeTime time.Duration // Max-Age of the session cookie (session.SetCookieLifeTime), 0 until the browser closes
+	MaxSessionAge  time.Duration // Config.MaxSessionAge of the store
+	Warnings       []string      // The mismatches, empty if the expiries agree
+}
+
+// CheckExpiry Starts a session with a manager of the go-session options opts against a probe
+// instead of the database and compares the row expiry, the cookie lifetime and MaxSessionAge.
+// The row expiry slides with every Update while the cookie is only set when a session starts,
+// every mismatch is returned and logged, e.g. a cookie that outlives its row
+func (s *ManagerStore) CheckExpiry(opts ...session.Option) (*ExpiryCheck, error) {
+	probe := new(expiryProbe)
+	manager := session.NewManager(append(opts, session.SetStore(probe))...)
+
+	r, err := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	if err != nil {
+		return nil, err
+	}
+	w := &headerWriter{header: make(http.Header)}
+	if _, err := manager.Start(context.Background(), w, r); err != nil {
+		return nil, err
+	}
+
+	check := &ExpiryCheck{
+		Expired:       time.Duration(probe.expired) * time.Second,
+		MaxSessionAge: s.maxAge,
+	}
+	cookies := (&http.Response{Header: w.header}).Cookies()
+	for _, cookie := range cookies {
+		if cookie.MaxAge > 0 {
+			check.CookieLifeTime = time.Duration(cookie.MaxAge) * time.Second
+		}
+	}
+	check.Warnings = expiryWarnings(check, len(cookies) > 0)
+
+	if logger := s.internalLogger(); logger != nil {
+		for _, warning := range check.Warnings {
+			logger.WarnContext(context.Background(), "gorm session: expiry mismatch", "op", "check_expiry", "warning", warning)
+		}
+	}
+	return check, nil
+}
+
+// expiryWarnings Returns the mismatches of the expiries of check,
+// the cookie is only compared if the manager sets it
+func expiryWarnings(check *ExpiryCheck, cookieSet bool) []string {
+	var warnings []string
+	expired, cookie, maxAge := check.Expired, check.CookieLifeTime, check.MaxSessionAge
+	switch {
+	case expired < 0:
+		warnings = append(warnings, fmt.Sprintf("the rows are created expired (%s)", expired))
+	case expired == 0 && maxAge == 0:
+		warnings = append(warnings, "the rows never expire, they are only deleted with their session")
+	case expired == 0 || !cookieSet:
+	case cookie == 0:
+		warnings = append(warnings, fmt.Sprintf("the cookie lives until the browser closes, "+
+			"a browser kept open keeps a cookie whose row expired after %s of inactivity", expired))
+	case cookie < expired:
+		warnings = append(warnings, fmt.Sprintf("the cookie expires after %s, before the row (%s): "+
+			"sessions end while they are active and the rows wait for GC", cookie, expired))
+	case cookie > expired:
+		warnings = append(warnings, fmt.Sprintf("the row expires after %s of inactivity, the cookie lives %s: "+
+			"returning users get a new session with a cookie that looked valid", expired, cookie))
+	}
+	if maxAge > 0 && cookie > maxAge {
+		warnings = append(warnings, fmt.Sprintf("the cookie lives %s, longer than MaxSessionAge (%s)", cookie, maxAge))
+	}
+	return warnings
+}
+
+// expiryProbe A session.ManagerStore recording the expiry of the session the manager creates
+type expiryProbe struct {
+	session.ManagerStore
+	expired int64
+}
+
+func (p *expiryProbe) Check(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func (p *expiryProbe) Create(_ context.Context, sid string, expired int64) (session.Store, error) {
+	p.expired = expired
+	return probeSession(sid), nil
+}
+
+// probeSession The session created by expiryProbe, the manager only reads its id
+type probeSession string
+
+func (p probeSession) Context() context.Context       { return context.Background() }
+func (p probeSession) SessionID() string              { return string(p) }
+func (p probeSession) Set(string, interface{})        {}
+func (p probeSession) Get(string) (interface{}, bool) { return nil, false }
+func (p probeSession) Delete(string) interface{}      { return nil }
+func (p probeSession) Save() error                    { return nil }
+func (p probeSession) Flush() error                   { return nil }
+
+// headerWriter A http.ResponseWriter keeping only the header, e.g. the cookie of the manager
+type headerWriter struct {
+	header http.Header
+}
+
+func (w *headerWriter) Header() http.Header           { return w.header }
+func (w *headerWriter) Write(buf []byte) (int, error) { return len(buf), nil }
+func (w *headerWriter) WriteHeader(int)               {}
@@ -0,0 +1,50 @@
+package gorm
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-session/session"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckExpiry(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_expiry_check", MaxSessionAge: 24 * time.Hour, NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test comparing the manager expiries with the store", t, func() {
+		check, err := mstore.CheckExpiry(session.SetExpired(3600), session.SetCookieLifeTime(3600))
+		So(err, ShouldBeNil)
+		So(check.Expired, ShouldEqual, time.Hour)
+		So(check.CookieLifeTime, ShouldEqual, time.Hour)
+		So(check.MaxSessionAge, ShouldEqual, 24*time.Hour)
+		So(check.Warnings, ShouldBeEmpty)
+
+		// the defaults of go-session: a cookie of 7 days for a row of 2 hours
+		check, err = mstore.CheckExpiry()
+		So(err, ShouldBeNil)
+		So(check.Expired, ShouldEqual, 2*time.Hour)
+		So(check.CookieLifeTime, ShouldEqual, 7*24*time.Hour)
+		So(check.Warnings, ShouldHaveLength, 2)
+
+		check, err = mstore.CheckExpiry(session.SetExpired(3600), session.SetCookieLifeTime(600))
+		So(err, ShouldBeNil)
+		So(check.Warnings, ShouldHaveLength, 1)
+		So(check.Warnings[0], ShouldContainSubstring, "before the row")
+
+		check, err = mstore.CheckExpiry(session.SetExpired(3600), session.SetCookieLifeTime(0))
+		So(err, ShouldBeNil)
+		So(check.Warnings, ShouldHaveLength, 1)
+		So(check.Warnings[0], ShouldContainSubstring, "until the browser closes")
+
+		check, err = mstore.CheckExpiry(session.SetExpired(3600), session.SetEnableSetCookie(false))
+		So(err, ShouldBeNil)
+		So(check.Warnings, ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,122 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ExtractRule Copies the value of a session key into an indexed column of the session table
+// on every save, e.g. "uid" into user_id, so sessions can be filtered by it
+type ExtractRule struct {
+	Key    string // Session key
+	Column string // Column name, a plain identifier
+	Size   int    // Size of the column (default 255)
+}
+
+const defaultExtractSize = 255
+
+// extractModel Returns the model used to migrate the extracted columns onto the session table,
+// every column is a nullable string
+func extractModel(rules []ExtractRule) interface{} {
+	fields := make([]reflect.StructField, len(rules))
+	for i, rule := range rules {
+		size := rule.Size
+		if size <= 0 {
+			size = defaultExtractSize
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("Extract%d", i),
+			Type: reflect.TypeOf((*string)(nil)),
+			Tag:  reflect.StructTag(fmt.Sprintf(`gorm:"column:%s;size:%d;"`, rule.Column, size)),
+		}
+	}
+	return reflect.New(reflect.StructOf(fields)).Interface()
+}
+
+// extractIndex Returns the name of the index of an extracted column, indexes are named per table
+func extractIndex(table, column string) string {
+	return "idx_" + table + "_" + column
+}
+
+// checkExtractRules Refuses columns that are not plain identifiers or that collide
+func checkExtractRules(rules []ExtractRule) error {
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if err := checkIdentifier(rule.Column); err != nil {
+			return err
+		} else if seen[rule.Column] {
+			return fmt.Errorf("gorm session: column %s is extracted twice", rule.Column)
+		}
+		seen[rule.Column] = true
+	}
+	return nil
+}
+
+// extractFields Adds the extracted columns of values to fields,
+// NULL for a missing key, strings as they are and other values as JSON
+func (s *ManagerStore) extractFields(values map[string]interface{}, fields map[string]interface{}) error {
+	for _, rule := range s.extract {
+		v, ok := values[rule.Key]
+		if !ok || v == nil {
+			fields[rule.Column] = nil
+			continue
+		} else if str, ok := v.(string); ok {
+			fields[rule.Column] = str
+			continue
+		}
+
+		buf, err := jsonMarshal(v)
+		if err != nil {
+			return err
+		}
+		fields[rule.Column] = string(buf)
+	}
+	return nil
+}
+
+// FindSessions Returns the sids of the live sessions whose extracted column holds value,
+// e.g. every session of a user for a forced logout
+func (s *ManagerStore) FindSessions(ctx context.Context, column string, value interface{}) ([]string, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, rule := range s.extract {
+		found = found || rule.Column == column
+	}
+	if !found {
+		return nil, fmt.Errorf("gorm session: column %s is not extracted", column)
+	}
+
+	if str, ok := value.(string); !ok {
+		buf, err := jsonMarshal(value)
+		if err != nil {
+			return nil, err
+		}
+		value = string(buf)
+	} else {
+		value = str
+	}
+
+	var keys []string
+	scope := s.db.NewScope(nil)
+	err = s.scoped().Where(scope.Quote(column)+"=? AND expired_at>?", value, time.Now()).Pluck("id", &keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sids := make([]string, len(keys))
+	for i, key := range keys {
+		sids[i] = s.sessionID(key)
+	}
+	return sids, nil
+}
@@ -0,0 +1,76 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExtractColumns(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName: "session_extract",
+		ExtractColumns: []ExtractRule{
+			{Key: "uid", Column: "user_id"},
+			{Key: "role", Column: "role", Size: 32},
+		},
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test extracting session keys into indexed columns", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		So(mstore.db.Dialect().HasIndex("session_extract", "idx_session_extract_user_id"), ShouldBeTrue)
+
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("uid", 42)
+		store.Set("role", "admin")
+		So(store.Save(), ShouldBeNil)
+
+		other, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		other.Set("uid", 43)
+		So(other.Save(), ShouldBeNil)
+
+		sids, err := mstore.FindSessions(ctx, "user_id", 42)
+		So(err, ShouldBeNil)
+		So(sids, ShouldResemble, []string{sid})
+		sids, err = mstore.FindSessions(ctx, "role", "admin")
+		So(err, ShouldBeNil)
+		So(sids, ShouldResemble, []string{sid})
+
+		newsid := newSid()
+		_, err = mstore.Refresh(ctx, sid, newsid, 60)
+		So(err, ShouldBeNil)
+		sids, err = mstore.FindSessions(ctx, "user_id", 42)
+		So(err, ShouldBeNil)
+		So(sids, ShouldResemble, []string{newsid})
+
+		store, err = mstore.Update(ctx, newsid, 60)
+		So(err, ShouldBeNil)
+		store.Delete("role")
+		So(store.Save(), ShouldBeNil)
+		sids, err = mstore.FindSessions(ctx, "role", "admin")
+		So(err, ShouldBeNil)
+		So(sids, ShouldBeEmpty)
+
+		_, err = mstore.FindSessions(ctx, "value", "x")
+		So(err, ShouldNotBeNil)
+
+		ddl, err := GenerateDDL("mysql", Config{ExtractColumns: []ExtractRule{{Key: "uid", Column: "user_id"}}})
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, "ALTER TABLE `session` ADD `user_id` varchar(255);")
+		So(ddl, ShouldContainSubstring, "CREATE INDEX idx_session_user_id ON `session`(`user_id`);")
+
+		_, err = NewManagerStore(Config{TableName: "session_extract", ExtractColumns: []ExtractRule{{Key: "x", Column: "x;--"}}}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,56 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFallbackTable(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	oldStore, err := NewManagerStore(Config{TableName: "session_old"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer oldStore.Close()
+
+	mstore, err := NewManagerStore(Config{TableName: "session_new", FallbackTableName: "session_old"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test read-through to the fallback table", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+
+		store, err := oldStore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+
+		So(store.Save(), ShouldBeNil)
+		exists, err = mstore.exists(mstore.db, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		exists, err = oldStore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,275 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrNoBackgroundGC Returned by SetGCInterval for a store that runs no background GC
+var ErrNoBackgroundGC = errors.New("gorm session: the background GC is not running")
+
+const (
+	// batchSize Limits the number of ids bound to one statement
+	batchSize = 500
+)
+
+// cleanExpiredBatches Walks the expired sessions in primary key order
+// and deletes them by primary key, gcBatchSize at a time
+func (s *ManagerStore) cleanExpiredBatches() (int64, error) {
+	return s.deleteBatches("expired_at<=?", time.Now())
+}
+
+// cleanAged Deletes the sessions created more than maxAge ago
+func (s *ManagerStore) cleanAged() (int64, error) {
+	cutoff := time.Now().Add(-s.maxAge)
+	if s.gcBatchSize > 0 || s.reportsExpiry() {
+		return s.deleteBatches("created_at<=?", cutoff)
+	}
+	result := s.scoped().Where("created_at<=?", cutoff).Delete(nil)
+	return result.RowsAffected, result.Error
+}
+
+// deleteBatches Walks the sessions matching the condition in primary key order
+// and deletes them by primary key, gcBatchSize (or batchSize) at a time,
+// reporting them to OnExpire and the subscribers
+func (s *ManagerStore) deleteBatches(cond string, arg interface{}) (int64, error) {
+	size := s.gcBatchSize
+	if size <= 0 {
+		size = batchSize
+	}
+
+	var last string
+	var deleted int64
+	for {
+		var ids []string
+		err := s.scoped().Where("id>?", last).Where(cond, arg).
+			Order("id").Limit(size).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return deleted, err
+		}
+
+		n, err := s.deleteIDs(ids)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		s.fireExpire(ids)
+		if len(ids) < size {
+			return deleted, nil
+		}
+		last = ids[len(ids)-1]
+	}
+}
+
+// evictOverflow Deletes the sessions closest to expiry until the table
+// holds no more than maxTableRows rows, reporting them to OnExpire and the subscribers
+func (s *ManagerStore) evictOverflow() (int64, error) {
+	var count int
+	err := s.scoped().Count(&count).Error
+	if err != nil || count <= s.maxTableRows {
+		return 0, err
+	}
+
+	var ids []string
+	err = s.scoped().Order("expired_at").Limit(count-s.maxTableRows).Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > batchSize {
+			n = batchSize
+		}
+
+		d, err := s.deleteIDs(ids[:n])
+		deleted += d
+		if err != nil {
+			return deleted, err
+		}
+		s.fireExpire(ids[:n])
+		ids = ids[n:]
+	}
+	return deleted, nil
+}
+
+// deleteIDs Deletes the sessions by primary key in batches
+func (s *ManagerStore) deleteIDs(ids []string) (int64, error) {
+	var deleted int64
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > batchSize {
+			n = batchSize
+		}
+
+		result := s.db.Where("id IN (?)", ids[:n]).Delete(nil)
+		if err := result.Error; err != nil {
+			return deleted, err
+		}
+		deleted += result.RowsAffected
+		ids = ids[n:]
+	}
+	return deleted, nil
+}
+
+// leadGC Reports whether this instance is the GC leader, trying to take the lead
+// if it is not, the lead is kept until the store is closed or its lock is lost
+func (s *ManagerStore) leadGC() bool {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	ctx := context.Background()
+	if s.gcLock != nil {
+		if s.gcLock.held(ctx) {
+			return true
+		}
+		// the lock went with its connection, another instance may lead by now
+		s.gcLock.release()
+		s.gcLock = nil
+	}
+
+	// the stores of other sid prefixes on the table collect their own sessions
+	lock, ok, err := s.lock(ctx, "gc", s.idPrefix, 0)
+	if err != nil {
+		s.errorf(err.Error())
+		return false
+	} else if ok {
+		s.gcLock = lock
+	}
+	return ok
+}
+
+// resignGC Gives up the GC lead so another instance can take it over
+func (s *ManagerStore) resignGC() {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	if s.gcLock != nil {
+		s.gcLock.release()
+		s.gcLock = nil
+	}
+}
+
+// GC Removes the expired sessions now, and runs the other GC tasks,
+// e.g. from a scheduled job of a store with Config.NoBackground
+func (s *ManagerStore) GC(_ context.Context) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+	s.root().runGC()
+	return nil
+}
+
+// SetGCInterval Changes the interval of the background GC at runtime, e.g. to slow it down
+// during an incident, the next run is scheduled the new interval from now
+func (s *ManagerStore) SetGCInterval(interval time.Duration) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	s = s.root()
+	if s.ticker == nil {
+		return ErrNoBackgroundGC
+	} else if interval <= 0 {
+		return fmt.Errorf("gorm session: GC interval %v is not positive", interval)
+	}
+
+	atomic.StoreInt64(&s.gcInterval, int64(interval))
+	s.ticker.Reset(s.nextGC())
+	return nil
+}
+
+// CleanExpired Deletes the expired sessions of the store now and returns their number,
+// e.g. from an admin endpoint, unlike GC it runs none of the other GC tasks
+func (s *ManagerStore) CleanExpired(ctx context.Context) (int64, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.deleteExpired()
+}
+
+// gcContext Returns the context of a GC run, canceled by Close or after GCTimeout
+func (s *ManagerStore) gcContext() (context.Context, context.CancelFunc) {
+	if s.gcTimeout > 0 {
+		return context.WithTimeout(s.gcCtx, s.gcTimeout)
+	}
+	return context.WithCancel(s.gcCtx)
+}
+
+// withContext Returns a copy of the store whose queries are canceled with ctx,
+// the store itself if its database is not a *sql.DB, e.g. a transaction
+func (s *ManagerStore) withContext(ctx context.Context) *ManagerStore {
+	sqlDB := s.sqlDB()
+	if sqlDB == nil {
+		return s
+	}
+
+	db, err := gorm.Open(s.db.Dialect().GetName(), contextDB{db: sqlDB, ctx: ctx})
+	if err != nil {
+		return s
+	}
+	// the database of the copy runs the callbacks registered on the store's
+	*db.Callback() = s.root().callbacks
+	db.BlockGlobalUpdate(s.db.HasBlockGlobalUpdate())
+	s.setSQLLogger(db)
+	if s.debug {
+		db = db.Debug()
+	}
+	return s.withDB(db.Table(s.tableName))
+}
+
+// sqlDB Returns the database the store runs its queries on, nil in a transaction
+func (s *ManagerStore) sqlDB() *sql.DB {
+	switch db := s.db.CommonDB().(type) {
+	case *sql.DB:
+		return db
+	case contextDB:
+		return db.db
+	}
+	return nil
+}
+
+// contextDB A gorm.SQLCommon that runs the statements of a *sql.DB with a context
+type contextDB struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+func (c contextDB) Begin() (*sql.Tx, error) {
+	return c.db.BeginTx(c.ctx, nil)
+}
+
+// BeginTx Begins the transaction with the context of the statements,
+// which is derived from the context of the operation
+func (c contextDB) BeginTx(_ context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.db.BeginTx(c.ctx, opts)
+}
+
+func (c contextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(c.ctx, query, args...)
+}
+
+func (c contextDB) Prepare(query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(c.ctx, query)
+}
+
+func (c contextDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(c.ctx, query, args...)
+}
+
+func (c contextDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(c.ctx, query, args...)
+}
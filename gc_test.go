@@ -0,0 +1,455 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaxTableRows(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	db.DropTableIfExists("session_capped")
+
+	var evicted []string
+	mstore, err := NewStoreWithConfig(db, Config{
+		TableName:    "session_capped",
+		MaxTableRows: 2,
+		OnExpire:     func(sid string) { evicted = append(evicted, sid) },
+	})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test GC evicts the sessions closest to expiry beyond the cap", t, func() {
+		ctx := context.Background()
+		var sids []string
+		for i := 0; i < 4; i++ {
+			sid := newSid()
+			sids = append(sids, sid)
+			store, err := mstore.Create(ctx, sid, int64(60*(i+1)))
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		mstore.clean()
+
+		for i, sid := range sids {
+			exists, err := mstore.Check(ctx, sid)
+			So(err, ShouldBeNil)
+			So(exists, ShouldEqual, i >= 2)
+		}
+		So(evicted, ShouldResemble, sids[:2])
+	})
+}
+
+func TestGCBatches(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_gc_batches", GCBatchSize: 2}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test GC deletes expired sessions by primary key in batches", t, func() {
+		ctx := context.Background()
+		var sids []string
+		for i := 0; i < 5; i++ {
+			sid := newSid()
+			sids = append(sids, sid)
+			store, err := mstore.Create(ctx, sid, -60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+		live := newSid()
+		defer mstore.Delete(ctx, live)
+		store, err := mstore.Create(ctx, live, 60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		mstore.clean()
+
+		checked, err := mstore.CheckMany(ctx, append(sids, live))
+		So(err, ShouldBeNil)
+		for _, sid := range sids {
+			So(checked[sid], ShouldBeFalse)
+		}
+		So(checked[live], ShouldBeTrue)
+	})
+}
+
+func TestMaxSessionAge(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_max_age", MaxSessionAge: time.Hour}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test sessions older than the maximum age are removed", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		err = mstore.db.Where("id=?", sid).Update("created_at", time.Now().Add(-2*time.Hour)).Error
+		So(err, ShouldBeNil)
+
+		store, err = mstore.Update(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		_, ok := store.Get("foo")
+		So(ok, ShouldBeFalse)
+
+		mstore.clean()
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+	})
+
+	Convey("Test a sid that aged out holds values again after a new save", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+		store, err := mstore.Create(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		err = mstore.db.Where("id=?", sid).Update("created_at", time.Now().Add(-2*time.Hour)).Error
+		So(err, ShouldBeNil)
+
+		store, err = mstore.Update(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		_, ok := store.Get("foo")
+		So(ok, ShouldBeFalse)
+		store.Set("foo", "baz")
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.Update(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "baz")
+	})
+}
+
+func TestGCIntervalDuration(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_gc_interval", GCInterval: 3600, GCIntervalDuration: 50 * time.Millisecond}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test the GC interval as a duration takes precedence", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		time.Sleep(200 * time.Millisecond)
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+	})
+}
+
+func TestNeverExpires(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_never_expires"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test sessions with an expiration of 0 are kept by GC", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 0)
+		So(err, ShouldBeNil)
+		store.Set("service", "billing")
+		So(store.Save(), ShouldBeNil)
+
+		var item SessionItem
+		So(mstore.db.Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		So(item.ExpiredAt.Equal(NeverExpires), ShouldBeTrue)
+
+		mstore.clean()
+		store, err = mstore.Update(ctx, sid, 0)
+		So(err, ShouldBeNil)
+		service, ok := store.Get("service")
+		So(ok, ShouldBeTrue)
+		So(service, ShouldEqual, "billing")
+	})
+}
+
+func TestNoBackground(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_no_background", NoBackground: true, GCProbability: 1}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test GC without a background goroutine", t, func() {
+		So(mstore.ticker, ShouldBeNil)
+
+		ctx := context.Background()
+		expired := newSid()
+		store, err := mstore.Create(ctx, expired, -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		// every save runs GC with a probability of 1
+		exists, err := mstore.exists(mstore.db, expired)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		mstore.gcProbability = 0
+		store, err = mstore.Create(ctx, expired, -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+		exists, err = mstore.exists(mstore.db, expired)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		So(mstore.GC(ctx), ShouldBeNil)
+		exists, err = mstore.exists(mstore.db, expired)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+	})
+}
+
+func TestNegativeGCInterval(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+
+	Convey("Test a negative GC interval disables the background GC", t, func() {
+		for _, cfg := range []Config{
+			{TableName: "session_gc_disabled", GCInterval: -1},
+			{TableName: "session_gc_disabled", GCIntervalDuration: -1},
+		} {
+			mstore, err := NewManagerStore(cfg, "sqlite3", dsn)
+			So(err, ShouldBeNil)
+			So(mstore.ticker, ShouldBeNil)
+
+			ctx := context.Background()
+			sid := newSid()
+			store, err := mstore.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+			exists, err := mstore.Check(ctx, sid)
+			So(err, ShouldBeNil)
+			So(exists, ShouldBeTrue)
+			So(mstore.Delete(ctx, sid), ShouldBeNil)
+			So(mstore.Close(), ShouldBeNil)
+		}
+	})
+}
+
+func TestCleanExpired(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_clean_expired", NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test deleting the expired sessions on demand", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		for _, expired := range []int64{-60, -60, 60} {
+			store, err := mstore.Create(ctx, newSid(), expired)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		deleted, err := mstore.CleanExpired(ctx)
+		So(err, ShouldBeNil)
+		So(deleted, ShouldEqual, 2)
+
+		deleted, err = mstore.CleanExpired(ctx)
+		So(err, ShouldBeNil)
+		So(deleted, ShouldEqual, 0)
+	})
+}
+
+func TestOnGC(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	db.DropTableIfExists("session_on_gc")
+
+	var results []GCResult
+	stdout := new(bytes.Buffer)
+	mstore, err := newManagerStore(db, Config{
+		TableName: "session_on_gc",
+		OnGC:      func(result GCResult) { results = append(results, result) },
+	}, stdout)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test GC passes the outcome of every run to OnGC", t, func() {
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			store, err := mstore.Create(ctx, newSid(), -60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		mstore.runGC()
+		So(results, ShouldHaveLength, 1)
+		So(results[0].Deleted, ShouldEqual, 3)
+		So(results[0].Err, ShouldBeNil)
+		So(results[0].Started.IsZero(), ShouldBeFalse)
+
+		// the errors are passed to the hook instead of the output
+		So(db.DropTable("session_on_gc").Error, ShouldBeNil)
+		mstore.runGC()
+		So(results, ShouldHaveLength, 2)
+		So(results[1].Deleted, ShouldEqual, 0)
+		So(results[1].Err, ShouldNotBeNil)
+		So(stdout.Len(), ShouldEqual, 0)
+	})
+}
+
+func TestGCJitter(t *testing.T) {
+	Convey("Test GC intervals varied by the jitter", t, func() {
+		mstore := &ManagerStore{gcInterval: int64(10 * time.Second)}
+		So(mstore.nextGC(), ShouldEqual, 10*time.Second)
+
+		mstore.gcJitter = 0.2
+		for i := 0; i < 100; i++ {
+			next := mstore.nextGC()
+			So(next, ShouldBeBetweenOrEqual, 8*time.Second, 12*time.Second)
+		}
+
+		_, err := NewManagerStore(Config{TableName: "session_gc_jitter", GCJitter: 1}, "sqlite3", os.TempDir()+"/gorm.db")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestSetGCInterval(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+
+	Convey("Test changing the GC interval at runtime", t, func() {
+		var runs int32
+		mstore, err := NewManagerStore(Config{
+			TableName:          "session_set_gc_interval",
+			GCIntervalDuration: time.Hour,
+			OnGC:               func(GCResult) { atomic.AddInt32(&runs, 1) },
+		}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		So(mstore.SetGCInterval(0), ShouldNotBeNil)
+		So(mstore.SetGCInterval(20*time.Millisecond), ShouldBeNil)
+		So(mstore.nextGC(), ShouldEqual, 20*time.Millisecond)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt32(&runs) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(atomic.LoadInt32(&runs), ShouldBeGreaterThanOrEqualTo, 2)
+
+		disabled, err := NewManagerStore(Config{TableName: "session_set_gc_interval", NoBackground: true}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer disabled.Close()
+		So(disabled.SetGCInterval(time.Minute), ShouldEqual, ErrNoBackgroundGC)
+	})
+}
+
+func TestGCTimeout(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+
+	Convey("Test GC queries canceled by the timeout and by Close", t, func() {
+		var results []GCResult
+		mstore, err := NewManagerStore(Config{
+			TableName:    "session_gc_timeout",
+			NoBackground: true,
+			GCTimeout:    time.Nanosecond,
+			OnGC:         func(result GCResult) { results = append(results, result) },
+		}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		mstore.runGC()
+		So(results, ShouldHaveLength, 1)
+		So(errors.Is(results[0].Err, context.DeadlineExceeded), ShouldBeTrue)
+		exists, err := mstore.exists(mstore.db, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		mstore.gcTimeout = time.Minute
+		mstore.runGC()
+		So(results, ShouldHaveLength, 2)
+		So(results[1].Err, ShouldBeNil)
+		So(results[1].Deleted, ShouldEqual, 1)
+
+		So(mstore.Close(), ShouldBeNil)
+		gcCtx, cancel := mstore.gcContext()
+		defer cancel()
+		So(gcCtx.Err(), ShouldEqual, context.Canceled)
+	})
+}
+
+func TestGCCallbacks(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var deletes int32
+	db.Callback().Delete().After("gorm:delete").Register("session:count_deletes", func(*gorm.Scope) {
+		atomic.AddInt32(&deletes, 1)
+	})
+	mstore, err := NewStoreWithConfig(db, Config{TableName: "session_gc_callbacks", NoBackground: true})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test GC queries running the callbacks of the database", t, func() {
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, newSid(), -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		mstore.runGC()
+		So(atomic.LoadInt32(&deletes), ShouldBeGreaterThan, 0)
+	})
+}
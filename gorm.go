@@ -2,11 +2,17 @@ package gorm
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/go-session/session"
@@ -14,12 +20,27 @@ import (
 )
 
 var (
-	_             session.ManagerStore = &managerStore{}
-	_             session.Store        = &store{}
+	_             session.ManagerStore = &ManagerStore{}
+	_             session.Store        = &Store{}
 	jsonMarshal                        = json.Marshal
 	jsonUnmarshal                      = json.Unmarshal
 )
 
+// ErrStoreClosed Returned by operations on a store that has been closed
+var ErrStoreClosed = errors.New("gorm session store is closed")
+
+// ErrTTLUnknown Returned by PreviewTTLChange before the store has seen the TTL of a session
+var ErrTTLUnknown = errors.New("gorm session: the TTL of the sessions is not known yet")
+
+// ErrSessionNotFound Returned by operations that require an existing session, e.g. MarkAuthenticated
+var ErrSessionNotFound = errors.New("gorm session: session not found")
+
+// NeverExpires The expiry stored for sessions with an expiration of 0, e.g. of service accounts,
+// GC never removes them
+var NeverExpires = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
 // SessionItem Data items stored in mysql
 type SessionItem struct {
 	ID        string    `gorm:"column:id;size:255;primary_key;"`
@@ -35,7 +56,255 @@ type Config struct {
 	MaxOpenConns    int           // sets the maximum number of open connections to the database
 	MaxIdleConns    int           // sets the maximum number of connections in the idle connection pool
 	TableName       string        // Specify the stored table name (default session)
-	GCInterval      int           // Time interval for executing GC (in seconds, default 600)
+	GCInterval      int           // Time interval for executing GC (in seconds, default 600, negative disables it)
+	Signer          Signer        // Sign stored values into a detached signature column (optional)
+
+	// GCIntervalDuration is the time interval for executing GC,
+	// it takes precedence over GCInterval when set, a negative interval starts
+	// no GC goroutine like NoBackground, e.g. when an external job cleans the table
+	GCIntervalDuration time.Duration
+
+	// GCJitter varies every GC interval randomly by up to this fraction of it,
+	// e.g. 0.2 for ±20%, so that replicas started together do not run GC at once
+	GCJitter float64
+
+	// ValueColumnSize is the size of the value column (default 2048),
+	// when it is set and the existing column is smaller, MigrateValueColumn
+	// alters the column, otherwise the store fails to start
+	ValueColumnSize    int
+	MigrateValueColumn bool
+
+	// ValueColumnType is the column type of the value column, e.g. TEXT, MEDIUMTEXT,
+	// BLOB or JSONB, it takes precedence over the size, which then only sets the OnLargeValue limit,
+	// an existing column of another type is altered with MigrateValueColumn.
+	// JSON and JSONB exclude compression and encryption, empty sessions are stored as {}
+	ValueColumnType string
+
+	// OnExpiring is called once per session that expires within ExpiryNoticeWindow,
+	// the scan runs after every GC and is tracked in the notified_at column (optional)
+	OnExpiring         func(sid string, expiredAt time.Time)
+	ExpiryNoticeWindow time.Duration
+
+	// OnCreate, OnRefresh and OnDelete are called once a session was created, moved to a new
+	// sid or deleted, after the commit inside WithTransaction, OnExpire by GC per session removed
+	// as expired or older than MaxSessionAge, e.g. to invalidate caches or to audit (optional)
+	OnCreate  func(sid string)
+	OnRefresh func(oldsid, sid string)
+	OnDelete  func(sid string)
+	OnExpire  func(sid string)
+
+	// EventBuffer is the capacity of the channels returned by Subscribe (default 64)
+	EventBuffer int
+
+	// TombstoneTTL keeps a marker of a deleted session in a table named after TableName with a
+	// _tombstones suffix for this long, a Save of the session meanwhile, e.g. in flight during
+	// a logout, is undone and fails with ErrSessionRevoked (optional)
+	TombstoneTTL time.Duration
+
+	// NotifyChannel makes Delete and Refresh NOTIFY the sid on this Postgres channel,
+	// other instances publish them to their subscribers by ListenPeers (optional)
+	NotifyChannel string
+
+	// OnGC is called after every GC run with its outcome, the errors of the run
+	// are then no longer written to the output (optional)
+	OnGC func(result GCResult)
+
+	// FallbackTableName is read when a session is missing from the table,
+	// sessions found there are copied forward on their next Save (optional)
+	FallbackTableName string
+
+	// MaxTableRows caps the number of rows, GC evicts the sessions
+	// closest to expiry beyond the cap (default 0, unlimited)
+	MaxTableRows int
+
+	// NegativeCacheTTL remembers sids that do not exist for the given time,
+	// so repeated lookups of invalid sids skip the database (default 0, disabled).
+	// Keep it short, sids created by other instances are not visible meanwhile.
+	NegativeCacheTTL  time.Duration
+	NegativeCacheSize int // Maximum number of remembered sids (default 10000)
+
+	// SIDPrefix is prepended to every sid stored by this store, so that several
+	// applications can share one table, GC and maintenance only see this prefix
+	SIDPrefix string
+
+	// GCTimeout bounds every GC run, whose queries are canceled when it runs out
+	// or the store is closed (default 0, no timeout)
+	GCTimeout time.Duration
+
+	// GCBatchSize makes GC select the expired ids in primary key order and delete them
+	// by primary key in batches of this size, which avoids long range locks on very
+	// large tables (default 0, a single DELETE by expired_at)
+	GCBatchSize int
+
+	// EnableLeases adds the lease columns used by AcquireLease and ReleaseLease
+	EnableLeases bool
+
+	// EnableIdempotencyKeys adds the idempotency key column used by CreateIdempotent
+	EnableIdempotencyKeys bool
+
+	// EnableChallenges creates the table of the one-time codes
+	// used by SetChallenge and VerifyChallenge, named after TableName with a _challenges suffix,
+	// the codes are stored as an HMAC-SHA256 under ChallengeKey, which it requires
+	EnableChallenges bool
+	ChallengeKey     []byte
+
+	// Compression is the tag of a registered Compressor applied to stored values
+	// (default 0, uncompressed), rows written with other compressors remain readable,
+	// values shorter than CompressionThreshold bytes are stored uncompressed (default 0, all)
+	Compression          byte
+	CompressionThreshold int
+
+	// CompressionDictionary compresses the values with deflate and the latest dictionary of
+	// TrainDictionary, stored in a table named after TableName with a _dicts suffix, ahead of
+	// Compression once a dictionary is trained, with the same CompressionThreshold (optional)
+	CompressionDictionary bool
+
+	// MaxSessionAge expires sessions created longer ago regardless of their activity,
+	// they are no longer loaded and GC removes them (default 0, unlimited)
+	MaxSessionAge time.Duration
+
+	// ExpiredPolicy selects how Update treats a session that is expired
+	// but not yet removed by GC (default ExpiredRecreate)
+	ExpiredPolicy ExpiredPolicy
+
+	// QuarantineUndecodable moves rows whose value fails to decrypt, decompress or unmarshal
+	// to a table named after TableName with a _quarantine suffix for offline analysis,
+	// the failures are counted by CodecErrors either way
+	QuarantineUndecodable bool
+
+	// AnalyzeSample makes GC sample this many live sessions once per AnalyzeInterval
+	// (default 1h) and report the frequency and size of their keys by ContentStats (optional)
+	AnalyzeSample   int
+	AnalyzeInterval time.Duration
+
+	// EmptyPolicy selects how Save treats a session without values (default EmptyKeep)
+	EmptyPolicy EmptyPolicy
+
+	// LogPoolStats logs the connection pool statistics after every GC run at info level,
+	// the wait and closed counts are the deltas since the previous run, and reports
+	// them to the Metrics by ObservePool
+	LogPoolStats bool
+
+	// SeparateValues keeps the values in a 1:1 table named after TableName with a _values suffix,
+	// so Check and GC only touch the narrow session table, values already stored inline are not moved
+	SeparateValues bool
+
+	// EnableLineage records every Refresh in a table named after TableName with a _lineage suffix,
+	// so Lineage can follow the rotation chain of a session, LineageRetention
+	// makes GC delete older records (default 0, kept forever)
+	EnableLineage    bool
+	LineageRetention time.Duration
+
+	// EnableStats records a snapshot of the session counts and the average value size
+	// after every GC run into a table named after TableName with a _stats suffix
+	EnableStats bool
+
+	// BumpPolicy decides whether Update extends the expiry of a session it reads,
+	// e.g. BumpWithProbability or BumpBelowRemaining trade exact sliding expiration
+	// for fewer writes (default nil, every read extends it)
+	BumpPolicy BumpPolicy
+
+	// PreserveCreatedAt keeps the created_at of a session through Refresh,
+	// e.g. as the original login time, MaxSessionAge then spans all rotations
+	PreserveCreatedAt bool
+
+	// TrackAuthentication adds the authenticated_at column set by MarkAuthenticated,
+	// which Refresh carries over to the new sid
+	TrackAuthentication bool
+
+	// Tables lists the table names WithTable may select besides TableName, each created
+	// on first use, the operations on other names fail with ErrTableNotAllowed
+	Tables []string
+
+	// StrictIdentifiers refuses table names, including those of Tables and WithTable,
+	// that are not plain identifiers of letters, digits and underscores
+	StrictIdentifiers bool
+
+	// NoBackground starts no GC goroutine, e.g. for serverless deployments, GC then runs
+	// on a fraction GCProbability of the saves (default 0, never) or when GC is called
+	NoBackground  bool
+	GCProbability float64
+
+	// OnLargeValue is called before a value whose size reaches ValueSizeWarningRatio
+	// (default 0.75) of the value column size is written, so bloating sessions
+	// are noticed before writes start failing (optional)
+	OnLargeValue          func(sid string, size, limit int)
+	ValueSizeWarningRatio float64
+
+	// DefaultValues returns the initial values of every session started by Create,
+	// e.g. the locale or a feature flag bucket (optional)
+	DefaultValues func(ctx context.Context) map[string]interface{}
+
+	// SkipIndexCreation leaves out the expired_at index when the table is created,
+	// which may lock a huge table, the index is then added by EnsureIndexes
+	SkipIndexCreation bool
+
+	// EncryptionKey encrypts the stored values with AES-GCM (16, 24 or 32 bytes for AES-128,
+	// AES-192 or AES-256), values written before it was set remain readable,
+	// DecryptionKeys are previous keys that still decrypt values during a key rotation
+	EncryptionKey  []byte
+	DecryptionKeys [][]byte
+
+	// TableTemplates replaces the CREATE TABLE statement of the session table by dialect name
+	// (mysql, postgres, sqlite3, mssql) with a text/template of TableTemplateData, e.g.
+	// "CREATE TABLE {{.Table}} ({{.Columns}}) TABLESPACE fast" (optional).
+	// The indexes and optional columns are still added by the store
+	TableTemplates map[string]string
+
+	// ExtractColumns copies session keys into indexed columns of the session table
+	// on every save, see FindSessions
+	ExtractColumns []ExtractRule
+
+	// CoordinateGC elects one GC leader among the instances sharing the table
+	// with a database advisory lock (mysql, postgres) that the leader holds until it is closed,
+	// the other instances skip their GC runs and take over once the lock is free
+	CoordinateGC bool
+
+	// VersionHandshake records the value formats every instance can read in a table named
+	// after TableName with a _meta suffix, refreshed on every GC run, and defers writing
+	// compressed or encrypted values while an instance seen within HandshakeWindow
+	// (default 15 minutes) cannot read them, e.g. during a rolling deploy.
+	// Instances of versions without the handshake are not detected.
+	VersionHandshake bool
+	HandshakeWindow  time.Duration
+
+	// UniqueUsersKey is the session key of the user id, whose hash is added on every save
+	// to a HyperLogLog sketch per UniqueUsersInterval (default 1 hour), the sketches are
+	// written on every GC run into a table named after TableName with a _users suffix,
+	// so UniqueUsers estimates the active users without storing their ids
+	UniqueUsersKey      string
+	UniqueUsersInterval time.Duration
+
+	// EnableAttempts creates a table named after TableName with a _attempts suffix
+	// for the counters of IncrementAttempt, which follow the session through Refresh
+	EnableAttempts bool
+
+	// LockSchema serializes the schema bootstrap of instances starting together
+	// with a database advisory lock (mysql, postgres), the other dialects rely on
+	// tolerating the errors of a concurrent bootstrap alone
+	LockSchema bool
+
+	// Logger receives structured entries of the operations at debug level, of GC runs
+	// at info level, of warnings and of errors, e.g. a *slog.Logger or NewWriterLogger,
+	// without it the errors and diagnostics are written to the output (stderr by default)
+	// in the lines of earlier versions, e.g. "[GORM-SESSION-ERROR]: <error>"; it also receives
+	// the SQL errors of gorm and, with Debug, its statements at debug level without
+	// their arguments instead of gorm printing them to stdout
+	Logger Logger
+
+	// Metrics receives the durations and errors of the operations and GC runs (optional)
+	Metrics MetricsSink
+
+	// MetricsLabel returns the label of an operation on the Metrics from its context
+	// or sid, e.g. the tenant or the section of the application, the labels that are
+	// not listed in MetricsLabels are reported as OtherLabel to bound the cardinality
+	// of the series, MetricsLabels is required with MetricsLabel (optional)
+	MetricsLabel  func(ctx context.Context, sid string) string
+	MetricsLabels []string
+
+	// Tracer starts a span per Create, Update, Refresh, Save, Delete and GC run with the
+	// table, the dialect and the affected rows, as a child of the span of the context (optional)
+	Tracer Tracer
 }
 
 // MustStore Create an instance of a gorm store(Throw a panic if an error occurs)
@@ -49,6 +318,25 @@ func MustStore(cfg Config, dialect string, args ...interface{}) session.ManagerS
 
 // NewStore Create an instance of a gorm store
 func NewStore(cfg Config, dialect string, args ...interface{}) (session.ManagerStore, error) {
+	store, err := NewManagerStore(cfg, dialect, args...)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// MustManagerStore Create an instance of a gorm store(Throw a panic if an error occurs)
+func MustManagerStore(cfg Config, dialect string, args ...interface{}) *ManagerStore {
+	store, err := NewManagerStore(cfg, dialect, args...)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+// NewManagerStore Create an instance of a gorm store, like NewStore,
+// returning the *ManagerStore for the methods beyond session.ManagerStore
+func NewManagerStore(cfg Config, dialect string, args ...interface{}) (*ManagerStore, error) {
 	db, err := gorm.Open(dialect, args...)
 	if err != nil {
 		return nil, err
@@ -58,252 +346,1340 @@ func NewStore(cfg Config, dialect string, args ...interface{}) (session.ManagerS
 		db = db.Debug()
 	}
 
-	err = db.DB().Ping()
-	if err != nil {
-		return nil, err
+	err = db.DB().Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	db.DB().SetMaxIdleConns(cfg.MaxIdleConns)
+	db.DB().SetMaxOpenConns(cfg.MaxOpenConns)
+	db.DB().SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return NewStoreWithConfig(db, cfg)
+}
+
+// MustStoreWithDB Create an instance of a gorm store(Throw a panic if an error occurs)
+func MustStoreWithDB(db *gorm.DB, tableName string, gcInterval int) session.ManagerStore {
+	store, err := NewStoreWithDB(db, tableName, gcInterval)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+// NewStoreWithDB Create an instance of a gorm store,
+// tableName Specify the stored table name (default session),
+// gcInterval Time interval for executing GC (in seconds, default 600)
+func NewStoreWithDB(db *gorm.DB, tableName string, gcInterval int) (session.ManagerStore, error) {
+	store, err := NewStoreWithConfig(db, Config{TableName: tableName, GCInterval: gcInterval})
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// MustStoreWithConfig Create an instance of a gorm store(Throw a panic if an error occurs)
+func MustStoreWithConfig(db *gorm.DB, cfg Config) *ManagerStore {
+	store, err := NewStoreWithConfig(db, cfg)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+// NewStoreWithConfig Create an instance of a gorm store on an existing db,
+// the connection pool settings of cfg are not applied
+func NewStoreWithConfig(db *gorm.DB, cfg Config) (*ManagerStore, error) {
+	return newManagerStore(db, cfg, os.Stderr)
+}
+
+// newManagerStore Create an instance of a gorm store that writes its errors to stdout
+func newManagerStore(db *gorm.DB, cfg Config, stdout io.Writer) (*ManagerStore, error) {
+	templates, err := parseTableTemplates(cfg.TableTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &ManagerStore{
+		tableName: "session",
+		stdout:    stdout,
+		done:      make(chan struct{}),
+		signer:    cfg.Signer,
+
+		onExpiring:    cfg.OnExpiring,
+		onGC:          cfg.OnGC,
+		onCreate:      cfg.OnCreate,
+		onRefresh:     cfg.OnRefresh,
+		onDelete:      cfg.OnDelete,
+		onExpire:      cfg.OnExpire,
+		eventBuffer:   cfg.EventBuffer,
+		notifyChannel: cfg.NotifyChannel,
+		tombstoneTTL:  cfg.TombstoneTTL,
+		noticeWindow:  cfg.ExpiryNoticeWindow,
+		fallbackTable: cfg.FallbackTableName,
+		maxTableRows:  cfg.MaxTableRows,
+		idPrefix:      cfg.SIDPrefix,
+		gcBatchSize:   cfg.GCBatchSize,
+		gcTimeout:     cfg.GCTimeout,
+		debug:         cfg.Debug,
+		leases:        cfg.EnableLeases,
+		compression:   cfg.Compression,
+		maxAge:        cfg.MaxSessionAge,
+		compressAbove: cfg.CompressionThreshold,
+
+		challengesEnabled: cfg.EnableChallenges,
+		logPool:           cfg.LogPoolStats,
+		skipIndexes:       cfg.SkipIndexCreation,
+		expiredPolicy:     cfg.ExpiredPolicy,
+		emptyPolicy:       cfg.EmptyPolicy,
+		quarantineEnabled: cfg.QuarantineUndecodable,
+		analyzeSample:     cfg.AnalyzeSample,
+		analyzeInterval:   cfg.AnalyzeInterval,
+		onLargeValue:      cfg.OnLargeValue,
+		warningRatio:      cfg.ValueSizeWarningRatio,
+		defaultValues:     cfg.DefaultValues,
+		separateValues:    cfg.SeparateValues,
+		lineageEnabled:    cfg.EnableLineage,
+		lineageRetention:  cfg.LineageRetention,
+		idempotency:       cfg.EnableIdempotencyKeys,
+		statsEnabled:      cfg.EnableStats,
+		bumpPolicy:        cfg.BumpPolicy,
+		preserveCreatedAt: cfg.PreserveCreatedAt,
+		trackAuth:         cfg.TrackAuthentication,
+		strict:            cfg.StrictIdentifiers,
+		gcProbability:     cfg.GCProbability,
+		logger:            cfg.Logger,
+		metrics:           cfg.Metrics,
+		labelFunc:         cfg.MetricsLabel,
+		tracer:            cfg.Tracer,
+		output:            newOutputLogger(stdout),
+		lockSchema:        cfg.LockSchema,
+		extract:           cfg.ExtractColumns,
+		tableTemplates:    templates,
+		coordinateGC:      cfg.CoordinateGC,
+		handshakeEnabled:  cfg.VersionHandshake,
+		handshakeWindow:   cfg.HandshakeWindow,
+		usersKey:          cfg.UniqueUsersKey,
+		usersInterval:     cfg.UniqueUsersInterval,
+		usersSketches:     make(map[time.Time][]byte),
+		attemptsEnabled:   cfg.EnableAttempts,
+		dictEnabled:       cfg.CompressionDictionary,
+
+		valueSize:    cfg.ValueColumnSize,
+		valueType:    cfg.ValueColumnType,
+		migrateValue: cfg.MigrateValueColumn,
+		columns:      optionalColumns(cfg),
+		tables:       make(map[string]*negativeCache),
+		allowed:      make(map[string]bool, len(cfg.Tables)),
+	}
+	store.gcCtx, store.gcCancel = context.WithCancel(context.Background())
+
+	if cfg.NegativeCacheTTL > 0 {
+		store.newCache = func() *negativeCache {
+			return newNegativeCache(cfg.NegativeCacheTTL, cfg.NegativeCacheSize)
+		}
+		store.missing = store.newCache()
+	}
+
+	if cfg.GCJitter < 0 || cfg.GCJitter >= 1 {
+		return nil, fmt.Errorf("gorm session: GC jitter %v is not in [0, 1)", cfg.GCJitter)
+	}
+
+	if cfg.NotifyChannel != "" && db.Dialect().GetName() != "postgres" {
+		return nil, fmt.Errorf("gorm session: NotifyChannel requires the postgres dialect")
+	}
+
+	if cfg.Compression != 0 {
+		if _, ok := lookupCompressor(cfg.Compression); !ok {
+			return nil, fmt.Errorf("gorm session: unknown compressor %d", cfg.Compression)
+		}
+	}
+
+	if cfg.EnableChallenges {
+		if len(cfg.ChallengeKey) == 0 {
+			return nil, errors.New("gorm session: EnableChallenges requires a ChallengeKey")
+		}
+		store.challengeKey = cfg.ChallengeKey
+	}
+
+	if cfg.MetricsLabel != nil {
+		if len(cfg.MetricsLabels) == 0 {
+			return nil, errors.New("gorm session: MetricsLabel requires MetricsLabels")
+		}
+		store.labels = make(map[string]bool, len(cfg.MetricsLabels))
+		for _, label := range cfg.MetricsLabels {
+			store.labels[label] = true
+		}
+	}
+
+	if cfg.EncryptionKey != nil {
+		ring, err := newKeyring(cfg.EncryptionKey, cfg.DecryptionKeys)
+		if err != nil {
+			return nil, err
+		}
+		store.keyring = ring
+	}
+
+	if err := checkExtractRules(cfg.ExtractColumns); err != nil {
+		return nil, err
+	} else if err := checkValueType(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.TableName != "" {
+		store.tableName = cfg.TableName
+	}
+	for _, name := range cfg.Tables {
+		store.allowed[name] = true
+	}
+	if store.strict {
+		for _, name := range append([]string{store.tableName, store.fallbackTable}, cfg.Tables...) {
+			if name == "" {
+				continue
+			} else if err := checkIdentifier(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	store.db = db.Table(store.tableName)
+	store.setSQLLogger(store.db)
+	store.callbacks = *db.Callback()
+
+	if err := store.initTable(); err != nil {
+		return nil, err
+	}
+	store.tables[store.tableName] = store.missing
+
+	if store.dictEnabled {
+		if err := store.loadDictionary(); err != nil {
+			return nil, err
+		}
+	}
+
+	if store.handshakeEnabled || store.usersKey != "" || store.notifyChannel != "" {
+		id, err := newInstanceID()
+		if err != nil {
+			return nil, err
+		}
+		store.instanceID = id
+	}
+	if store.usersInterval <= 0 {
+		store.usersInterval = defaultUniqueUsersInterval
+	}
+	if store.handshakeEnabled {
+		if store.handshakeWindow <= 0 {
+			store.handshakeWindow = defaultHandshakeWindow
+		}
+		if err := store.handshake(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.NoBackground || cfg.GCIntervalDuration < 0 || (cfg.GCIntervalDuration == 0 && cfg.GCInterval < 0) {
+		return store, nil
+	}
+
+	interval := time.Second * 600
+	if cfg.GCIntervalDuration > 0 {
+		interval = cfg.GCIntervalDuration
+	} else if cfg.GCInterval > 0 {
+		interval = time.Second * time.Duration(cfg.GCInterval)
+	}
+	store.gcInterval = int64(interval)
+	store.gcJitter = cfg.GCJitter
+	store.ticker = time.NewTicker(store.nextGC())
+
+	go store.gc()
+	return store, nil
+}
+
+// ManagerStore A gorm implementation of session.ManagerStore
+type ManagerStore struct {
+	ticker    *time.Ticker
+	wg        sync.WaitGroup
+	db        *gorm.DB
+	callbacks gorm.Callback // of db, for the copies of withContext
+	tableName string
+	stdout    io.Writer
+	done      chan struct{}
+	closed    int32
+	signer    Signer
+	forUpdate bool
+
+	gcInterval int64 // time.Duration, accessed atomically for SetGCInterval
+	ttl        int64 // in seconds, the expiry of the latest session, accessed atomically
+	gcJitter   float64
+
+	onExpiring    func(sid string, expiredAt time.Time)
+	onGC          func(result GCResult)
+	onCreate      func(sid string)
+	onRefresh     func(oldsid, sid string)
+	onDelete      func(sid string)
+	onExpire      func(sid string)
+	txHooks       *[]func()
+	eventBuffer   int
+	notifyChannel string
+	tombstoneTTL  time.Duration
+	subsMu        sync.Mutex
+	subs          map[<-chan SessionEvent]chan SessionEvent
+	subscribers   int32
+	droppedEvents int64
+	noticeWindow  time.Duration
+	draining      int32
+	fallbackTable string
+	maxTableRows  int
+	missing       *negativeCache
+	idPrefix      string
+	gcBatchSize   int
+	gcTimeout     time.Duration
+	gcCtx         context.Context
+	gcCancel      context.CancelFunc
+	debug         bool
+	leases        bool
+	compression   byte
+	maxAge        time.Duration
+	compressAbove int
+	parent        *ManagerStore
+	unbound       *ManagerStore // the store a budget-bound copy was made of
+
+	challengesEnabled bool
+	challengeKey      []byte
+	logPool           bool
+	poolStats         sql.DBStats
+	skipIndexes       bool
+	expiredPolicy     ExpiredPolicy
+	emptyPolicy       EmptyPolicy
+	quarantineEnabled bool
+	codecMu           sync.Mutex
+	codecErrors       map[string]int64
+	analyzeSample     int
+	analyzeInterval   time.Duration
+	contentMu         sync.Mutex
+	content           *ContentReport
+	onLargeValue      func(sid string, size, limit int)
+	warningRatio      float64
+	defaultValues     func(ctx context.Context) map[string]interface{}
+	separateValues    bool
+	lineageEnabled    bool
+	lineageRetention  time.Duration
+	idempotency       bool
+	statsEnabled      bool
+	bumpPolicy        BumpPolicy
+	preserveCreatedAt bool
+	trackAuth         bool
+	strict            bool
+	gcProbability     float64
+	gcRunning         int32
+	gcMu              sync.Mutex
+	gcStatus          GCStatus
+	keyring           *keyring
+	logger            Logger
+	metrics           MetricsSink
+	labelFunc         func(ctx context.Context, sid string) string
+	labels            map[string]bool
+	tracer            Tracer
+	strong            bool
+	output            Logger
+	lockSchema        bool
+	extract           []ExtractRule
+	tableTemplates    map[string]*template.Template
+	coordinateGC      bool
+	gcLock            *advisoryLock
+	handshakeEnabled  bool
+	handshakeWindow   time.Duration
+	instanceID        string
+	deferredMask      uint32
+	usersKey          string
+	usersInterval     time.Duration
+	usersMu           sync.Mutex
+	usersSketches     map[time.Time][]byte
+	attemptsEnabled   bool
+	dictEnabled       bool
+	dictMu            sync.RWMutex
+	dicts             map[string][]byte
+	dictID            string
+
+	valueSize    int
+	valueType    string
+	migrateValue bool
+	columns      []interface{}
+	tablesMu     sync.Mutex
+	tables       map[string]*negativeCache
+	allowed      map[string]bool
+	newCache     func() *negativeCache
+}
+
+// initTable Creates the table of the store if it does not exist yet,
+// and adds the columns of the enabled features
+func (s *ManagerStore) initTable() error {
+	if s.lockSchema {
+		lock, ok, err := s.lock(context.Background(), "schema", "", schemaLockTimeout)
+		if err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("gorm session: timed out waiting for the schema lock of table %s", s.tableName)
+		}
+		defer lock.release()
+	}
+
+	model := sessionModel(s.valueSize, s.valueType)
+	if s.separateValues {
+		model = &sessionMeta{}
+	}
+
+	if !s.db.HasTable(s.tableName) {
+		// Another instance may create the table concurrently,
+		// so a failed create is only fatal if the table is still missing.
+		err := s.createTable(model)
+		if err != nil && !s.db.HasTable(s.tableName) {
+			return err
+		}
+		if !s.skipIndexes {
+			s.db.AddIndex(expiredIndex(s.tableName), "expired_at")
+		}
+	} else if (s.valueSize > 0 || s.valueType != "") && !s.separateValues {
+		err := s.checkValueColumn(s.tableName, s.valueSize, s.migrateValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, columns := range s.columns {
+		err := s.autoMigrate(s.db, columns)
+		if err != nil {
+			return err
+		}
+	}
+	for _, rule := range s.extract {
+		s.db.AddIndex(extractIndex(s.tableName, rule.Column), rule.Column)
+	}
+
+	if s.separateValues {
+		err := s.initValueTable()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.challengesEnabled {
+		err := s.initChallengeTable()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.lineageEnabled {
+		err := s.initLineageTable()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.statsEnabled {
+		err := s.autoMigrate(s.stats(), &statsItem{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.handshakeEnabled {
+		err := s.autoMigrate(s.db.Table(metaTable(s.tableName)), &metaItem{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.usersKey != "" {
+		err := s.autoMigrate(s.users(), &usersItem{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.attemptsEnabled {
+		err := s.autoMigrate(s.attempts(), &attemptItem{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.quarantineEnabled {
+		err := s.autoMigrate(s.quarantine(), resizeValue(&quarantineItem{}, s.valueSize, s.valueType))
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.dictEnabled {
+		err := s.autoMigrate(s.dictionaries(), &dictionaryItem{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.tombstoneTTL > 0 {
+		err := s.autoMigrate(s.tombstones(), &tombstoneItem{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ManagerStore) gc() {
+	for {
+		select {
+		case <-s.ticker.C:
+			s.runGC()
+			if s.gcJitter > 0 {
+				s.ticker.Reset(s.nextGC())
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// nextGC Returns the time until the next GC run, the interval varied by the jitter
+func (s *ManagerStore) nextGC() time.Duration {
+	interval := time.Duration(atomic.LoadInt64(&s.gcInterval))
+	if s.gcJitter == 0 {
+		return interval
+	}
+	return time.Duration(float64(interval) * (1 + s.gcJitter*(2*rand.Float64()-1)))
+}
+
+// runGC Runs one GC pass, unless another one is in progress
+func (s *ManagerStore) runGC() {
+	if !atomic.CompareAndSwapInt32(&s.gcRunning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&s.gcRunning, 0)
+
+	if s.coordinateGC && !s.leadGC() {
+		return
+	}
+
+	ctx, cancel := s.gcContext()
+	defer cancel()
+
+	start := time.Now()
+	span := s.startSpan(ctx, "gc")
+	gs := s.withContext(ctx)
+	deleted, gcErr := gs.clean()
+	if s.onExpiring != nil {
+		gs.notifyExpiring()
+	}
+	if s.logPool {
+		gs.logPoolStats()
+	}
+	if s.statsEnabled {
+		s.gcError(&gcErr, gs.recordStats())
+	}
+	if s.handshakeEnabled {
+		s.gcError(&gcErr, gs.handshake())
+	}
+	if s.usersKey != "" {
+		s.gcError(&gcErr, gs.flushUsers())
+	}
+	if s.analyzeSample > 0 {
+		s.gcError(&gcErr, s.analyzeIfDue(gs))
+	}
+	if s.dictEnabled {
+		s.gcError(&gcErr, gs.loadDictionary())
+	}
+
+	s.gcMu.Lock()
+	s.gcStatus.Runs++
+	s.gcStatus.LastRun = start
+	s.gcStatus.Duration = time.Since(start)
+	s.gcStatus.Deleted = deleted
+	s.gcMu.Unlock()
+	s.logGC(start, deleted)
+	s.observeGC(start, deleted, gcErr)
+	span.setRows(deleted)
+	span.end(&gcErr)
+
+	if s.onGC != nil {
+		s.onGC(GCResult{
+			Started:  start,
+			Duration: time.Since(start),
+			Deleted:  deleted,
+			Err:      gcErr,
+		})
+	}
+}
+
+func (s *ManagerStore) isClosed() bool {
+	if s.parent != nil {
+		return s.parent.isClosed()
+	}
+	return atomic.LoadInt32(&s.closed) == 1
+}
+
+// root Returns the store that owns the GC and the shared state
+func (s *ManagerStore) root() *ManagerStore {
+	if s.parent != nil {
+		return s.parent
+	}
+	return s
+}
+
+// withDB Returns a copy of the store that runs its queries on db,
+// the copy does not own the GC and must not be closed
+func (s *ManagerStore) withDB(db *gorm.DB) *ManagerStore {
+	return &ManagerStore{
+		db:        db,
+		tableName: s.tableName,
+		stdout:    s.stdout,
+		signer:    s.signer,
+		forUpdate: s.forUpdate,
+
+		onExpiring:    s.onExpiring,
+		noticeWindow:  s.noticeWindow,
+		fallbackTable: s.fallbackTable,
+		maxTableRows:  s.maxTableRows,
+		gcBatchSize:   s.gcBatchSize,
+		onGC:          s.onGC,
+		onCreate:      s.onCreate,
+		onRefresh:     s.onRefresh,
+		onDelete:      s.onDelete,
+		onExpire:      s.onExpire,
+		txHooks:       s.txHooks,
+		notifyChannel: s.notifyChannel,
+		tombstoneTTL:  s.tombstoneTTL,
+		missing:       s.missing,
+		idPrefix:      s.idPrefix,
+		leases:        s.leases,
+		compression:   s.compression,
+		maxAge:        s.maxAge,
+		compressAbove: s.compressAbove,
+		parent:        s.root(),
+
+		challengesEnabled: s.challengesEnabled,
+		challengeKey:      s.challengeKey,
+		skipIndexes:       s.skipIndexes,
+		expiredPolicy:     s.expiredPolicy,
+		emptyPolicy:       s.emptyPolicy,
+		quarantineEnabled: s.quarantineEnabled,
+		onLargeValue:      s.onLargeValue,
+		warningRatio:      s.warningRatio,
+		defaultValues:     s.defaultValues,
+		separateValues:    s.separateValues,
+		lineageEnabled:    s.lineageEnabled,
+		lineageRetention:  s.lineageRetention,
+		idempotency:       s.idempotency,
+		statsEnabled:      s.statsEnabled,
+		bumpPolicy:        s.bumpPolicy,
+		preserveCreatedAt: s.preserveCreatedAt,
+		trackAuth:         s.trackAuth,
+		strict:            s.strict,
+		gcProbability:     s.gcProbability,
+		keyring:           s.keyring,
+		logger:            s.logger,
+		metrics:           s.metrics,
+		labelFunc:         s.labelFunc,
+		labels:            s.labels,
+		tracer:            s.tracer,
+		output:            s.output,
+		lockSchema:        s.lockSchema,
+		extract:           s.extract,
+		tableTemplates:    s.tableTemplates,
+		usersKey:          s.usersKey,
+		usersInterval:     s.usersInterval,
+		attemptsEnabled:   s.attemptsEnabled,
+		dictEnabled:       s.dictEnabled,
+
+		valueSize:    s.valueSize,
+		valueType:    s.valueType,
+		migrateValue: s.migrateValue,
+		columns:      s.columns,
+	}
+}
+
+// key Returns the primary key of sid
+func (s *ManagerStore) key(sid string) string {
+	return s.idPrefix + sid
+}
+
+// sessionID Returns the sid of a primary key
+func (s *ManagerStore) sessionID(key string) string {
+	return strings.TrimPrefix(key, s.idPrefix)
+}
+
+// scoped Returns a query limited to the sessions of this store's prefix
+func (s *ManagerStore) scoped() *gorm.DB {
+	return s.scope(s.db)
+}
+
+// scope Restricts the rows of db to the ids of the sid prefix
+func (s *ManagerStore) scope(db *gorm.DB) *gorm.DB {
+	if s.idPrefix == "" {
+		return db
+	}
+	return db.Where("id LIKE ? ESCAPE '!'", escapeLike(s.idPrefix)+"%")
+}
+
+// escapeLike Escapes the wildcards of a LIKE pattern with '!'
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// clean Runs the GC tasks, returns the number of deleted sessions
+func (s *ManagerStore) clean() (deleted int64, err error) {
+	wg := &s.root().wg
+	wg.Add(1)
+	defer wg.Done()
+
+	deleted, cerr := s.deleteExpired()
+	s.gcError(&err, cerr)
+	if s.maxAge > 0 {
+		n, cerr := s.cleanAged()
+		s.gcError(&err, cerr)
+		deleted += n
+	}
+	s.missing.purge()
+	if s.maxTableRows > 0 {
+		n, cerr := s.evictOverflow()
+		s.gcError(&err, cerr)
+		deleted += n
+	}
+	if s.challengesEnabled {
+		s.gcError(&err, s.cleanChallenges())
+	}
+	if s.separateValues {
+		s.gcError(&err, s.cleanValues())
+	}
+	if s.lineageEnabled && s.lineageRetention > 0 {
+		s.gcError(&err, s.cleanLineage())
+	}
+	if s.attemptsEnabled {
+		s.gcError(&err, s.cleanAttempts())
+	}
+	if s.leases {
+		s.gcError(&err, s.releaseStaleLeases())
+	}
+	if s.tombstoneTTL > 0 {
+		s.gcError(&err, s.cleanTombstones())
+	}
+	return deleted, err
+}
+
+// deleteExpired Deletes the expired sessions, in batches with gcBatchSize or when they are reported
+func (s *ManagerStore) deleteExpired() (int64, error) {
+	if s.gcBatchSize > 0 || s.reportsExpiry() {
+		return s.cleanExpiredBatches()
+	}
+
+	db := s.scoped().Where("expired_at<=?", time.Now())
+
+	var count int
+	err := db.Count(&count).Error
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	result := db.Delete(nil)
+	return result.RowsAffected, result.Error
+}
+
+// gcError Keeps the first error of a GC run in first, the errors are written
+// to the output unless they are passed to Config.OnGC
+func (s *ManagerStore) gcError(first *error, err error) {
+	if err == nil {
+		return
+	} else if *first == nil {
+		*first = err
+	}
+	if s.onGC == nil {
+		s.errorf(err.Error())
+	}
+}
+
+func (s *ManagerStore) errorf(format string, args ...interface{}) {
+	if logger := s.internalLogger(); logger != nil {
+		logger.ErrorContext(context.Background(), "gorm session: error", "error", fmt.Sprintf(format, args...))
 	}
-
-	db.DB().SetMaxIdleConns(cfg.MaxIdleConns)
-	db.DB().SetMaxOpenConns(cfg.MaxOpenConns)
-	db.DB().SetConnMaxLifetime(cfg.ConnMaxLifetime)
-	return NewStoreWithDB(db, cfg.TableName, cfg.GCInterval)
 }
 
-// MustStoreWithDB Create an instance of a gorm store(Throw a panic if an error occurs)
-func MustStoreWithDB(db *gorm.DB, tableName string, gcInterval int) session.ManagerStore {
-	store, err := NewStoreWithDB(db, tableName, gcInterval)
-	if err != nil {
-		panic(err)
+func (s *ManagerStore) getValue(key string) (string, error) {
+	item, err := s.getItem(key)
+	if err != nil || item == nil {
+		return "", err
+	} else if item.ExpiredAt.Before(time.Now()) || s.tooOld(item.CreatedAt) {
+		return "", nil
 	}
-	return store
+	return item.Value, nil
 }
 
-// NewStoreWithDB Create an instance of a gorm store,
-// tableName Specify the stored table name (default session),
-// gcInterval Time interval for executing GC (in seconds, default 600)
-func NewStoreWithDB(db *gorm.DB, tableName string, gcInterval int) (session.ManagerStore, error) {
-	store := &managerStore{
-		tableName: "session",
-		stdout:    os.Stderr,
+// getItem Returns the row of key including expired rows, nil if it does not exist,
+// database errors are returned rather than treated as a missing row
+func (s *ManagerStore) getItem(key string) (*SessionItem, error) {
+	if s.cachedMissing(key) {
+		return nil, nil
 	}
 
-	if tableName != "" {
-		store.tableName = tableName
+	db := s.db
+	if s.forUpdate {
+		db = db.Set("gorm:query_option", "FOR UPDATE")
 	}
-	store.db = db.Table(store.tableName)
 
-	if !db.HasTable(store.tableName) {
-		err := store.db.CreateTable(&SessionItem{}).Error
+	var item SessionItem
+	err := db.Where("id=?", key).First(&item).Error
+	if err == nil && s.separateValues {
+		err := s.loadValue(&item)
 		if err != nil {
 			return nil, err
 		}
-		store.db.AddIndex("idx_expired_at", "expired_at")
+	} else if err == gorm.ErrRecordNotFound && s.fallbackTable != "" {
+		err = s.db.Table(s.fallbackTable).Where("id=?", key).First(&item).Error
 	}
-
-	interval := 600
-	if gcInterval > 0 {
-		interval = gcInterval
+	if err == gorm.ErrRecordNotFound {
+		s.missing.add(key)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
-	store.ticker = time.NewTicker(time.Second * time.Duration(interval))
+	return &item, nil
+}
 
-	go store.gc()
-	return store, nil
+// tooOld Reports whether a session created at createdAt exceeds MaxSessionAge
+func (s *ManagerStore) tooOld(createdAt time.Time) bool {
+	return s.maxAge > 0 && !createdAt.After(time.Now().Add(-s.maxAge))
 }
 
-type managerStore struct {
-	ticker    *time.Ticker
-	wg        sync.WaitGroup
-	db        *gorm.DB
-	tableName string
-	stdout    io.Writer
+func (s *ManagerStore) parseValue(value string) (map[string]interface{}, error) {
+	values, _, err := s.decodeValues(value)
+	return values, err
 }
 
-func (s *managerStore) gc() {
-	for range s.ticker.C {
-		s.clean()
+// GetExpired Returns the expiry of a session that lives for expired seconds,
+// NeverExpires for an expiration of 0
+func (s *ManagerStore) GetExpired(expired int64) time.Time {
+	if expired == 0 {
+		return NeverExpires
 	}
+	return time.Now().Add(time.Duration(expired) * time.Second)
 }
 
-func (s *managerStore) clean() {
-	s.wg.Add(1)
-	defer s.wg.Done()
+// expiryFields Returns the columns to update when extending a session,
+// nothing while the store is draining
+func (s *ManagerStore) expiryFields(expired int64) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if s.isDraining() {
+		return fields
+	}
 
-	db := s.db.Where("expired_at<=?", time.Now())
+	fields["expired_at"] = s.GetExpired(expired)
+	if s.onExpiring != nil {
+		// an extended session may be notified again before its new expiry
+		fields["notified_at"] = nil
+	}
+	return fields
+}
 
-	var count int
-	err := db.Count(&count).Error
-	if err != nil || count == 0 {
-		if err != nil {
-			s.errorf(err.Error())
-		}
-		return
+// Check Reports whether the session exists and is neither expired nor beyond MaxSessionAge,
+// only sids without a row are remembered by the negative cache
+func (s *ManagerStore) Check(ctx context.Context, sid string) (_ bool, err error) {
+	if s.isClosed() {
+		return false, ErrStoreClosed
 	}
 
-	err = db.Delete(nil).Error
+	defer charge(ctx)()
+	defer s.logOp(ctx, "check", sid, time.Now(), &err)
+	s, err = s.forContext(ctx)
 	if err != nil {
-		s.errorf(err.Error())
+		return false, err
+	}
+
+	key := s.key(sid)
+	if s.cachedMissing(key) {
+		return false, nil
 	}
-}
 
-func (s *managerStore) errorf(format string, args ...interface{}) {
-	if s.stdout != nil {
-		buf := fmt.Sprintf("[GORM-SESSION-ERROR]: "+format, args...)
-		s.stdout.Write([]byte(buf))
+	found, err := s.existing(s.db, []string{key})
+	if err == nil && len(found) == 0 && s.fallbackTable != "" {
+		found, err = s.existing(s.db.Table(s.fallbackTable), []string{key})
 	}
+	if err == nil && len(found) == 0 {
+		s.missing.add(key)
+	}
+	return found[key], err
 }
 
-func (s *managerStore) getValue(sid string) (string, error) {
-	var item SessionItem
-	err := s.db.Where("id=?", sid).First(&item).Error
+// CheckMany Checks several sessions like Check with one IN query per batch of sids
+func (s *ManagerStore) CheckMany(ctx context.Context, sids []string) (map[string]bool, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return "", nil
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(sids))
+	keys := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		result[sid] = false
+		if key := s.key(sid); !s.cachedMissing(key) {
+			keys = append(keys, key)
 		}
-	} else if item.ExpiredAt.Before(time.Now()) {
-		return "", nil
 	}
-	return item.Value, nil
-}
 
-func (s *managerStore) parseValue(value string) (map[string]interface{}, error) {
-	var values map[string]interface{}
-	if len(value) > 0 {
-		err := jsonUnmarshal([]byte(value), &values)
+	found, err := s.existing(s.db, keys)
+	if err != nil {
+		return nil, err
+	}
+	if s.fallbackTable != "" {
+		var rest []string
+		for _, key := range keys {
+			if _, ok := found[key]; !ok {
+				rest = append(rest, key)
+			}
+		}
+
+		fallback, err := s.existing(s.db.Table(s.fallbackTable), rest)
 		if err != nil {
 			return nil, err
 		}
+		for key, live := range fallback {
+			found[key] = live
+		}
 	}
 
-	return values, nil
+	for _, key := range keys {
+		if live, ok := found[key]; ok {
+			result[s.sessionID(key)] = live
+		} else {
+			s.missing.add(key)
+		}
+	}
+	return result, nil
 }
 
-func (s *managerStore) GetExpired(expired int64) time.Time {
-	return time.Now().Add(time.Duration(expired) * time.Second)
+// existing Returns the subset of keys that exist in db, mapped to whether
+// the session is live, i.e. neither expired nor beyond MaxSessionAge
+func (s *ManagerStore) existing(db *gorm.DB, keys []string) (map[string]bool, error) {
+	found := make(map[string]bool)
+	now := time.Now()
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > batchSize {
+			n = batchSize
+		}
+
+		var items []SessionItem
+		err := db.Select("id, created_at, expired_at").Where("id IN (?)", keys[:n]).Find(&items).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			found[item.ID] = item.ExpiredAt.After(now) && !s.tooOld(item.CreatedAt)
+		}
+		keys = keys[n:]
+	}
+	return found, nil
 }
 
-func (s *managerStore) Check(_ context.Context, sid string) (bool, error) {
+func (s *ManagerStore) exists(db *gorm.DB, key string) (bool, error) {
 	var count int
-	result := s.db.Where("id=?", sid).Count(&count)
+	result := db.Where("id=?", key).Count(&count)
 	if err := result.Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (s *managerStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
-	return newStore(ctx, s, sid, expired, nil), nil
+func (s *ManagerStore) Create(ctx context.Context, sid string, expired int64) (_ session.Store, err error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	defer s.logOp(ctx, "create", sid, time.Now(), &err)
+	span := s.startSpan(ctx, "create")
+	defer span.end(&err)
+	s, err = s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.missing.remove(s.key(sid))
+	s.fireCreate(sid)
+
+	return newStore(ctx, s, sid, expired, s.initialValues(ctx)), nil
 }
 
-func (s *managerStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
-	value, err := s.getValue(sid)
+func (s *ManagerStore) Update(ctx context.Context, sid string, expired int64) (_ session.Store, err error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	defer s.logOp(ctx, "update", sid, time.Now(), &err)
+	span := s.startSpan(ctx, "update")
+	defer span.end(&err)
+	s, err = s.forContext(ctx)
 	if err != nil {
 		return nil, err
-	} else if value == "" {
-		return newStore(ctx, s, sid, expired, nil), nil
 	}
 
-	result := s.db.Where("id=?", sid).Update("expired_at", s.GetExpired(expired))
-	if err := result.Error; err != nil {
+	item, err := s.getItem(s.key(sid))
+	if err != nil {
 		return nil, err
+	} else if item == nil {
+		return newStore(ctx, s, sid, expired, nil), nil
+	} else if s.tooOld(item.CreatedAt) {
+		// the row must go, a later Save would keep its creation time
+		// and the session could never hold values again
+		if _, err := s.deleteSession(item.ID); err != nil {
+			return nil, err
+		}
+		s.fireExpire([]string{item.ID})
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+	span.setRows(1)
+	if item.ExpiredAt.Before(time.Now()) {
+		return s.updateExpired(ctx, sid, expired, item)
+	}
+
+	value := item.Value
+	if value == "" {
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+
+	extended := false
+	if fields := s.expiryFields(expired); len(fields) > 0 && s.shouldBump(item, expired) {
+		result := s.db.Where("id=?", s.key(sid)).Updates(fields)
+		if err := result.Error; err != nil {
+			return nil, err
+		}
+		extended = true
 	}
 
-	values, err := s.parseValue(value)
+	values, err := s.parseRow(s.key(sid), value)
 	if err != nil {
 		return nil, err
 	}
 
-	return newStore(ctx, s, sid, expired, values), nil
+	return newStore(ctx, s, sid, expired, values).loaded(item, extended), nil
+}
+
+func (s *ManagerStore) Delete(ctx context.Context, sid string) (err error) {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	defer s.logOp(ctx, "delete", sid, time.Now(), &err)
+	span := s.startSpan(ctx, "delete")
+	defer span.end(&err)
+	s, err = s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(sid)
+	if err := s.bury(key); err != nil {
+		return err
+	}
+	n, err := s.deleteSession(key)
+	if err != nil {
+		return err
+	}
+	span.setRows(n)
+	if n == 0 {
+		return nil
+	}
+	if err := s.notifyPeers("delete", sid); err != nil {
+		return err
+	}
+	s.fireDelete(sid)
+	return nil
 }
 
-func (s *managerStore) Delete(_ context.Context, sid string) error {
-	result := s.db.Where("id=?", sid).Delete(nil)
-	return result.Error
+// deleteSession Deletes the row key with its value, attempts and challenges,
+// and returns the number of deleted session rows
+func (s *ManagerStore) deleteSession(key string) (int64, error) {
+	result := s.db.Where("id=?", key).Delete(nil)
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+	n := result.RowsAffected
+
+	if s.separateValues {
+		result = s.values().Where("id=?", key).Delete(nil)
+		if err := result.Error; err != nil {
+			return n, err
+		}
+	}
+	if s.attemptsEnabled {
+		result = s.attempts().Where("session_id=?", key).Delete(nil)
+		if err := result.Error; err != nil {
+			return n, err
+		}
+	}
+	if s.challengesEnabled {
+		result = s.sessionChallenges(key).Delete(nil)
+		if err := result.Error; err != nil {
+			return n, err
+		}
+	}
+	if s.fallbackTable == "" {
+		return n, nil
+	}
+
+	// the session must not be read through from the fallback table again
+	result = s.db.Table(s.fallbackTable).Where("id=?", key).Delete(nil)
+	return n, result.Error
 }
 
-func (s *managerStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
-	value, err := s.getValue(oldsid)
+func (s *ManagerStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (_ session.Store, err error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	defer s.logOp(ctx, "refresh", oldsid, time.Now(), &err)
+	span := s.startSpan(ctx, "refresh")
+	defer span.end(&err)
+	s, err = s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oldkey := s.key(oldsid)
+	old, err := s.getItem(oldkey)
 	if err != nil {
 		return nil, err
-	} else if value == "" {
+	} else if old == nil || old.Value == "" || old.ExpiredAt.Before(time.Now()) || s.tooOld(old.CreatedAt) {
 		return newStore(ctx, s, sid, expired, nil), nil
 	}
+	value := old.Value
 
+	createdAt := time.Now()
+	if s.preserveCreatedAt {
+		createdAt = old.CreatedAt
+	}
+
+	key := s.key(sid)
 	item := &SessionItem{
-		ID:        sid,
+		ID:        key,
 		Value:     value,
-		CreatedAt: time.Now(),
+		CreatedAt: createdAt,
 		ExpiredAt: s.GetExpired(expired),
 	}
-	result := s.db.Create(item)
-	if err := result.Error; err != nil {
+	err = s.createItem(item)
+	if err != nil {
 		return nil, err
 	}
+	s.missing.remove(key)
+	span.setRows(1)
+
+	fields := make(map[string]interface{})
+	if s.signer != nil {
+		signature, err := s.signer.Sign(key, []byte(value))
+		if err != nil {
+			return nil, err
+		}
+		fields["signature"] = signature
+	}
+	if s.trackAuth {
+		at, err := s.authenticatedAt(oldkey)
+		if err != nil {
+			return nil, err
+		} else if at != nil {
+			fields["authenticated_at"] = *at
+		}
+	}
 
-	err = s.Delete(nil, oldsid)
+	values, err := s.parseRow(oldkey, value)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.extractFields(values, fields); err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		result := s.db.Where("id=?", key).Updates(fields)
+		if err := result.Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if s.lineageEnabled {
+		err := s.lineage().Create(&lineageItem{
+			OldID:       oldkey,
+			NewID:       key,
+			RefreshedAt: time.Now(),
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.attemptsEnabled {
+		result := s.attempts().Where("session_id=?", oldkey).Update("session_id", key)
+		if err := result.Error; err != nil {
+			return nil, err
+		}
+	}
+	if s.challengesEnabled {
+		err := s.moveChallenges(oldkey, key)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	values, err := s.parseValue(value)
+	err = s.bury(oldkey)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.deleteSession(oldkey)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.notifyPeers("refresh", oldsid); err != nil {
+		return nil, err
+	}
+	s.fireRefresh(oldsid, sid)
+
+	return newStore(ctx, s, sid, expired, values).loaded(item, false), nil
+}
+
+// PreviewTTLChange Reports how many currently-live sessions would already
+// have expired had newTTL been in effect instead of the current TTL, the expiry
+// the manager passed for the latest session. The last activity of a session
+// is derived from its expiry minus the current TTL.
+func (s *ManagerStore) PreviewTTLChange(ctx context.Context, newTTL time.Duration) (int64, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	}
+
+	currentTTL := time.Duration(atomic.LoadInt64(&s.root().ttl)) * time.Second
+	if currentTTL <= 0 {
+		return 0, ErrTTLUnknown
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
 
-	return newStore(ctx, s, sid, expired, values), nil
+	now := time.Now()
+	var count int64
+	result := s.scoped().Where("expired_at>? AND expired_at<=?", now, now.Add(currentTTL-newTTL)).Count(&count)
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
-func (s *managerStore) Close() error {
-	s.ticker.Stop()
+// Close Stops the GC and closes the database,
+// calling it again does nothing and returns nil
+func (s *ManagerStore) Close() error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	close(s.done)
+	s.gcCancel()
 	s.wg.Wait()
+	s.closeSubscribers()
+	s.resignGC()
+	if s.handshakeEnabled {
+		s.resign()
+	}
+	if s.usersKey != "" {
+		s.flushUsers()
+	}
 	s.db.Close()
 	return nil
 }
 
-func newStore(ctx context.Context, s *managerStore, sid string, expired int64, values map[string]interface{}) *store {
+func newStore(ctx context.Context, s *ManagerStore, sid string, expired int64, values map[string]interface{}) *Store {
 	if values == nil {
 		values = make(map[string]interface{})
 	}
+	if expired > 0 {
+		atomic.StoreInt64(&s.root().ttl, expired)
+	}
 
-	return &store{
-		ctx:     ctx,
-		mstore:  s,
-		sid:     sid,
-		expired: expired,
-		values:  values,
+	return &Store{
+		ctx:       ctx,
+		mstore:    s.unbind(),
+		sid:       sid,
+		expired:   expired,
+		values:    values,
+		createdAt: time.Now(),
+		expiresAt: s.GetExpired(expired),
 	}
 }
 
-type store struct {
+// loaded Takes the creation time of the session from its row,
+// and the expiry too unless it was extended on load
+func (s *Store) loaded(item *SessionItem, extended bool) *Store {
+	s.createdAt = item.CreatedAt
+	if !extended {
+		s.expiresAt = item.ExpiredAt
+	}
+	return s
+}
+
+// Store A gorm implementation of session.Store
+type Store struct {
 	sync.RWMutex
-	ctx     context.Context
-	mstore  *managerStore
-	sid     string
-	expired int64
-	values  map[string]interface{}
+	ctx       context.Context
+	mstore    *ManagerStore
+	sid       string
+	expired   int64
+	values    map[string]interface{}
+	createdAt time.Time
+	expiresAt time.Time
 }
 
-func (s *store) Context() context.Context {
+func (s *Store) Context() context.Context {
 	return s.ctx
 }
 
-func (s *store) SessionID() string {
+func (s *Store) SessionID() string {
 	return s.sid
 }
 
-func (s *store) Set(key string, value interface{}) {
+// CreatedAt Returns when the session was created,
+// for a session that is not saved yet it is the time it was started
+func (s *Store) CreatedAt() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return s.createdAt
+}
+
+// ExpiresAt Returns when the session expires as of the last load or save
+func (s *Store) ExpiresAt() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return s.expiresAt
+}
+
+func (s *Store) Set(key string, value interface{}) {
 	s.Lock()
 	s.values[key] = value
 	s.Unlock()
 }
 
-func (s *store) Get(key string) (interface{}, bool) {
+func (s *Store) Get(key string) (interface{}, bool) {
 	s.RLock()
 	val, ok := s.values[key]
 	s.RUnlock()
 	return val, ok
 }
 
-func (s *store) Delete(key string) interface{} {
+func (s *Store) Delete(key string) interface{} {
 	s.RLock()
 	v, ok := s.values[key]
 	s.RUnlock()
@@ -315,14 +1691,90 @@ func (s *store) Delete(key string) interface{} {
 	return v
 }
 
-func (s *store) Flush() error {
+// Apply Runs fn on the session values under the write lock,
+// so a multi-key update is not interleaved with concurrent Sets.
+// fn works on a deep copy that replaces the values only if fn returns nil.
+func (s *Store) Apply(fn func(values map[string]interface{}) error) error {
+	s.Lock()
+	defer s.Unlock()
+
+	values := copyValues(s.values)
+	if err := fn(values); err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+
+func (s *Store) Flush() error {
 	s.Lock()
 	s.values = make(map[string]interface{})
 	s.Unlock()
 	return s.Save()
 }
 
-func (s *store) Save() error {
+func (s *Store) Save() error {
+	return s.SaveWithOptions(SaveOptions{})
+}
+
+// SaveOptions Options of a single save
+type SaveOptions struct {
+	Expired    int64 // Overrides the expiry of the session for this save (in seconds)
+	KeepExpiry bool  // Writes the values without touching the expiry of an existing session
+}
+
+// SaveWithOptions Save the session values with per-call options,
+// e.g. extend a session to 30 days once "remember me" is checked
+func (s *Store) SaveWithOptions(opts SaveOptions) (err error) {
+	if err := checkBudget(s.ctx); err != nil {
+		return err
+	}
+	defer charge(s.ctx)()
+	defer s.mstore.logOp(s.ctx, "save", s.sid, time.Now(), &err)
+	span := s.mstore.startSpan(s.ctx, "save")
+	defer span.end(&err)
+
+	item, fields, err := s.row(opts)
+	if err != nil {
+		return err
+	}
+
+	mstore := s.mstore.bind(s.ctx)
+	if mstore.isClosed() {
+		return ErrStoreClosed
+	} else if ok, err := s.saveEmpty(mstore); ok || err != nil {
+		return err
+	}
+
+	if err := mstore.writeItem(s.ctx, item, fields); err != nil {
+		return err
+	}
+	if err := mstore.checkRevoked(item.ID); err != nil {
+		return err
+	}
+	mstore.missing.remove(item.ID)
+	s.saved(item, fields)
+	span.setRows(1)
+
+	if s.mstore.usersKey != "" {
+		uid, _ := s.Get(s.mstore.usersKey)
+		s.mstore.observeUser(uid)
+	}
+
+	if p := s.mstore.gcProbability; p > 0 && rand.Float64() < p {
+		s.mstore.root().runGC()
+	}
+	return nil
+}
+
+// row Returns the row of the session values and the fields to update
+// if the row exists already
+func (s *Store) row(opts SaveOptions) (*SessionItem, map[string]interface{}, error) {
+	expired := s.expired
+	if opts.Expired > 0 {
+		expired = opts.Expired
+	}
+
 	var value string
 
 	s.RLock()
@@ -330,31 +1782,57 @@ func (s *store) Save() error {
 		buf, err := jsonMarshal(s.values)
 		if err != nil {
 			s.RUnlock()
-			return err
+			s.mstore.codecFailed(codecMarshal, s.sid, err)
+			return nil, nil, err
 		}
 		value = string(buf)
 	}
 	s.RUnlock()
 
-	exists, err := s.mstore.Check(nil, s.sid)
+	value, err := s.mstore.encodeValue(value)
 	if err != nil {
-		return err
-	} else if !exists {
-		item := &SessionItem{
-			ID:        s.sid,
-			Value:     value,
-			CreatedAt: time.Now(),
-			ExpiredAt: s.mstore.GetExpired(s.expired),
-		}
-		result := s.mstore.db.Create(item)
-		if err := result.Error; err != nil {
-			return err
+		return nil, nil, err
+	}
+	s.mstore.checkValueSize(s.sid, value)
+
+	key := s.mstore.key(s.sid)
+	fields := make(map[string]interface{})
+	if !opts.KeepExpiry {
+		fields = s.mstore.expiryFields(expired)
+	}
+	if !s.mstore.separateValues {
+		fields["value"] = value
+	}
+	if len(s.mstore.extract) > 0 {
+		s.RLock()
+		err := s.mstore.extractFields(s.values, fields)
+		s.RUnlock()
+		if err != nil {
+			return nil, nil, err
 		}
 	}
+	if signer := s.mstore.signer; signer != nil {
+		signature, err := signer.Sign(key, []byte(value))
+		if err != nil {
+			return nil, nil, err
+		}
+		fields["signature"] = signature
+	}
+
+	item := &SessionItem{
+		ID:        key,
+		Value:     value,
+		CreatedAt: time.Now(),
+		ExpiredAt: s.mstore.GetExpired(expired),
+	}
+	return item, fields, nil
+}
 
-	result := s.mstore.db.Where("id=?", s.sid).Updates(map[string]interface{}{
-		"value":      value,
-		"expired_at": s.mstore.GetExpired(s.expired),
-	})
-	return result.Error
+// saved Takes the expiry of the session from the row it was saved with
+func (s *Store) saved(item *SessionItem, fields map[string]interface{}) {
+	if _, ok := fields["expired_at"]; ok {
+		s.Lock()
+		s.expiresAt = item.ExpiredAt
+		s.Unlock()
+	}
 }
@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -84,6 +85,26 @@ func testStore(t *testing.T, mstore session.ManagerStore) {
 	So(ok, ShouldBeTrue)
 	So(foo2, ShouldEqual, "bar2")
 
+	err = store.(*Store).Apply(func(values map[string]interface{}) error {
+		values["foo"] = "bar"
+		delete(values, "foo2")
+		return nil
+	})
+	So(err, ShouldBeNil)
+	foo, ok = store.Get("foo")
+	So(ok, ShouldBeTrue)
+	So(foo, ShouldEqual, "bar")
+	_, ok = store.Get("foo2")
+	So(ok, ShouldBeFalse)
+
+	err = store.(*Store).Apply(func(values map[string]interface{}) error {
+		values["foo"] = "baz"
+		return errors.New("abort")
+	})
+	So(err, ShouldNotBeNil)
+	foo, _ = store.Get("foo")
+	So(foo, ShouldEqual, "bar")
+
 	err = store.Flush()
 	So(err, ShouldBeNil)
 
@@ -124,6 +145,10 @@ func testManagerStore(t *testing.T, mstore session.ManagerStore) {
 	So(exists, ShouldBeFalse)
 	So(err, ShouldBeNil)
 
+	checked, err := mstore.(*ManagerStore).CheckMany(ctx, []string{sid, newsid})
+	So(err, ShouldBeNil)
+	So(checked, ShouldResemble, map[string]bool{sid: false, newsid: true})
+
 	err = mstore.Delete(ctx, newsid)
 	So(err, ShouldBeNil)
 
@@ -153,3 +178,228 @@ func testGC(t *testing.T, mstore session.ManagerStore) {
 	So(exists, ShouldBeFalse)
 	So(err, ShouldBeNil)
 }
+
+func TestPreviewTTLChange(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_preview"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test preview of a TTL change", t, func() {
+		ctx := context.Background()
+		_, err := mstore.PreviewTTLChange(ctx, 5*time.Second)
+		So(err, ShouldEqual, ErrTTLUnknown)
+
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 10)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		count, err := mstore.PreviewTTLChange(ctx, 5*time.Second)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+
+		// the session was last saved 10s ago under a TTL of 20s
+		So(mstore.db.Where("id=?", sid).Update("expired_at", time.Now().Add(10*time.Second)).Error, ShouldBeNil)
+		_, err = mstore.Create(ctx, newSid(), 20)
+		So(err, ShouldBeNil)
+		count, err = mstore.PreviewTTLChange(ctx, 5*time.Second)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+	})
+}
+
+func TestStoreClose(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_close"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	Convey("Test closing a store twice", t, func() {
+		So(mstore.Close(), ShouldBeNil)
+		So(mstore.Close(), ShouldBeNil)
+
+		_, err := mstore.Check(context.Background(), newSid())
+		So(err, ShouldEqual, ErrStoreClosed)
+	})
+}
+
+func TestSaveWithOptions(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_save_options"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test per-call expiry options of a save", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("remember", true)
+		So(store.(*Store).SaveWithOptions(SaveOptions{Expired: 30 * 24 * 3600}), ShouldBeNil)
+
+		var item SessionItem
+		So(mstore.db.Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		So(item.ExpiredAt.After(time.Now().Add(29*24*time.Hour)), ShouldBeTrue)
+
+		store.Set("foo", "bar")
+		So(store.(*Store).SaveWithOptions(SaveOptions{KeepExpiry: true}), ShouldBeNil)
+
+		var kept SessionItem
+		So(mstore.db.Where("id=?", sid).First(&kept).Error, ShouldBeNil)
+		So(kept.Value, ShouldContainSubstring, "bar")
+		So(kept.ExpiredAt.Equal(item.ExpiredAt), ShouldBeTrue)
+	})
+}
+
+func TestSIDPrefix(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_shared", SIDPrefix: "app_1:"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	other, err := NewManagerStore(Config{TableName: "session_shared"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer other.Close()
+
+	Convey("Test sessions are partitioned by the sid prefix", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		So(store.SessionID(), ShouldEqual, sid)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := other.Check(ctx, "app_1:"+sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+		exists, err = other.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		othersid := newSid()
+		defer other.Delete(ctx, othersid)
+		store, err = other.Create(ctx, othersid, -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		mstore.clean()
+		exists, err = other.exists(other.db, othersid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+	})
+}
+
+func TestDefaultValues(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName: "session_defaults",
+		DefaultValues: func(ctx context.Context) map[string]interface{} {
+			return map[string]interface{}{"locale": "en"}
+		},
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test initial values of new sessions", t, func() {
+		ctx := context.Background()
+		store, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		locale, ok := store.Get("locale")
+		So(ok, ShouldBeTrue)
+		So(locale, ShouldEqual, "en")
+		store.Set("locale", "de")
+
+		other, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		locale, _ = other.Get("locale")
+		So(locale, ShouldEqual, "en")
+	})
+}
+
+func TestSessionTimes(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_times"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test creation and expiry times of a session", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		item, err := mstore.getItem(mstore.key(sid))
+		So(err, ShouldBeNil)
+
+		time.Sleep(time.Second)
+		store, err = mstore.Update(ctx, sid, 3600)
+		So(err, ShouldBeNil)
+		createdAt := store.(*Store).CreatedAt()
+		So(createdAt.Unix(), ShouldEqual, item.CreatedAt.Unix())
+		expiresAt := store.(*Store).ExpiresAt()
+		So(expiresAt.Sub(createdAt), ShouldBeGreaterThan, time.Hour)
+
+		err = store.(*Store).SaveWithOptions(SaveOptions{Expired: 7200})
+		So(err, ShouldBeNil)
+		So(store.(*Store).ExpiresAt().Sub(createdAt), ShouldBeGreaterThan, 2*time.Hour)
+	})
+}
+
+func TestDatabaseErrors(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_db_errors"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	Convey("Test database errors are not mistaken for missing sessions", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		So(mstore.db.DropTable("session_db_errors").Error, ShouldBeNil)
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldNotBeNil)
+		_, err = mstore.getValue(mstore.key(sid))
+		So(err, ShouldNotBeNil)
+
+		So(mstore.Close(), ShouldBeNil)
+		_, err = mstore.Update(ctx, sid, 60)
+		So(errors.Is(err, ErrStoreClosed), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,104 @@
+package gorm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// The value formats an instance can read, new formats are only written
+// once every live instance advertises them with Config.VersionHandshake
+const (
+	featureCompression = "compression"
+	featureEncryption  = "encryption"
+	featureDictionary  = "dictionary"
+)
+
+// libraryFeatures The value formats this version of the library reads, in bit order
+var libraryFeatures = []string{featureCompression, featureEncryption, featureDictionary}
+
+const defaultHandshakeWindow = 15 * time.Minute
+
+// metaItem The handshake row of an instance, refreshed on every GC run
+type metaItem struct {
+	Instance string    `gorm:"column:instance;size:64;primary_key;"`
+	Features string    `gorm:"column:features;size:1024;"`
+	SeenAt   time.Time `gorm:"column:seen_at;"`
+}
+
+// metaTable Returns the name of the handshake table of a session table
+func metaTable(tableName string) string {
+	return tableName + "_meta"
+}
+
+func newInstanceID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// handshake Records the features of this instance and defers the configured
+// features that an instance seen within the handshake window cannot read
+func (s *ManagerStore) handshake() error {
+	root := s.root()
+	db := s.db.Table(metaTable(s.tableName))
+	now := time.Now()
+	err := db.Save(&metaItem{
+		Instance: root.instanceID,
+		Features: strings.Join(libraryFeatures, ","),
+		SeenAt:   now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var items []metaItem
+	err = db.Where("seen_at>?", now.Add(-root.handshakeWindow)).Find(&items).Error
+	if err != nil {
+		return err
+	}
+
+	var deferred uint32
+	for _, item := range items {
+		features := strings.Split(item.Features, ",")
+		for i, feature := range libraryFeatures {
+			if !containsString(features, feature) {
+				deferred |= 1 << uint(i)
+			}
+		}
+	}
+	atomic.StoreUint32(&root.deferredMask, deferred)
+	return nil
+}
+
+// resign Removes the handshake row of this instance
+func (s *ManagerStore) resign() error {
+	return s.db.Table(metaTable(s.tableName)).Where("instance=?", s.instanceID).Delete(&metaItem{}).Error
+}
+
+// deferred Reports whether feature must not be written yet
+func (s *ManagerStore) deferred(feature string) bool {
+	mask := atomic.LoadUint32(&s.root().deferredMask)
+	for i, f := range libraryFeatures {
+		if f == feature {
+			return mask&(1<<uint(i)) != 0
+		}
+	}
+	return false
+}
+
+// DeferredFeatures Returns the value formats, e.g. compression or encryption,
+// that are not written yet because an instance seen within the handshake window cannot read them
+func (s *ManagerStore) DeferredFeatures() []string {
+	var features []string
+	for _, feature := range libraryFeatures {
+		if s.deferred(feature) {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
@@ -0,0 +1,57 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVersionHandshake(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName:        "session_handshake",
+		VersionHandshake: true,
+		EncryptionKey:    bytes.Repeat([]byte{1}, 32),
+		NoBackground:     true,
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test new value formats are deferred while an instance cannot read them", t, func() {
+		ctx := context.Background()
+		meta := mstore.db.Table(metaTable("session_handshake"))
+		So(meta.Where("instance<>?", mstore.instanceID).Delete(&metaItem{}).Error, ShouldBeNil)
+		So(mstore.DeferredFeatures(), ShouldBeEmpty)
+
+		old := &metaItem{Instance: "old", Features: featureCompression, SeenAt: time.Now()}
+		So(meta.Create(old).Error, ShouldBeNil)
+		So(mstore.GC(ctx), ShouldBeNil)
+		So(mstore.DeferredFeatures(), ShouldResemble, []string{featureEncryption, featureDictionary})
+
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("email", "foo@example.com")
+		So(store.Save(), ShouldBeNil)
+		item, err := mstore.getItem(mstore.key(sid))
+		So(err, ShouldBeNil)
+		So(strings.Contains(item.Value, "foo@example.com"), ShouldBeTrue)
+
+		So(meta.Where("instance=?", "old").Update("seen_at", time.Now().Add(-time.Hour)).Error, ShouldBeNil)
+		So(mstore.GC(ctx), ShouldBeNil)
+		So(mstore.DeferredFeatures(), ShouldBeEmpty)
+
+		So(store.Save(), ShouldBeNil)
+		item, err = mstore.getItem(mstore.key(sid))
+		So(err, ShouldBeNil)
+		So(strings.Contains(item.Value, "foo@example.com"), ShouldBeFalse)
+	})
+}
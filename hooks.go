@@ -0,0 +1,66 @@
+package gorm
+
+// fire Calls a lifecycle hook, or queues it until the transaction
+// of the store commits so that a rolled back change is never reported
+func (s *ManagerStore) fire(hook func()) {
+	if s.txHooks != nil {
+		*s.txHooks = append(*s.txHooks, hook)
+		return
+	}
+	hook()
+}
+
+// fireCreate Reports a created session to Config.OnCreate
+func (s *ManagerStore) fireCreate(sid string) {
+	if s.onCreate != nil {
+		s.fire(func() { s.onCreate(sid) })
+	}
+}
+
+// fireRefresh Reports a refreshed session to Config.OnRefresh and the subscribers
+func (s *ManagerStore) fireRefresh(oldsid, sid string) {
+	if s.onRefresh == nil && !s.subscribed() {
+		return
+	}
+	s.fire(func() {
+		if s.onRefresh != nil {
+			s.onRefresh(oldsid, sid)
+		}
+		s.publish(EventRefreshed, oldsid)
+	})
+}
+
+// fireDelete Reports a deleted session to Config.OnDelete and the subscribers
+func (s *ManagerStore) fireDelete(sid string) {
+	if s.onDelete == nil && !s.subscribed() {
+		return
+	}
+	s.fire(func() {
+		if s.onDelete != nil {
+			s.onDelete(sid)
+		}
+		s.publish(EventDeleted, sid)
+	})
+}
+
+// reportsExpiry Reports whether the sessions removed by GC must be reported one by one
+func (s *ManagerStore) reportsExpiry() bool {
+	return s.onExpire != nil || s.subscribed()
+}
+
+// fireExpire Reports the sessions of the rows ids removed by GC, MaxSessionAge or
+// ExpiredDelete to Config.OnExpire and the subscribers
+func (s *ManagerStore) fireExpire(ids []string) {
+	if !s.reportsExpiry() {
+		return
+	}
+	s.fire(func() {
+		for _, id := range ids {
+			sid := s.sessionID(id)
+			if s.onExpire != nil {
+				s.onExpire(sid)
+			}
+			s.publish(EventExpired, sid)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	reset := func() {
+		mu.Lock()
+		events = nil
+		mu.Unlock()
+	}
+	recorded := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events...)
+	}
+
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName: "session_hooks",
+		OnCreate:  func(sid string) { record("create " + sid) },
+		OnRefresh: func(oldsid, sid string) { record("refresh " + oldsid + " " + sid) },
+		OnDelete:  func(sid string) { record("delete " + sid) },
+		OnExpire:  func(sid string) { record("expire " + sid) },
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test the hooks of the session lifecycle", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		reset()
+		oldsid, sid := newSid(), newSid()
+
+		store, err := mstore.Create(ctx, oldsid, expired)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		_, err = mstore.Refresh(ctx, oldsid, sid, expired)
+		So(err, ShouldBeNil)
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+
+		So(recorded(), ShouldResemble, []string{
+			"create " + oldsid,
+			"refresh " + oldsid + " " + sid,
+			"delete " + sid,
+		})
+	})
+
+	Convey("Test the hooks run after the commit of a transaction", t, func() {
+		ctx := context.Background()
+		reset()
+		sid := newSid()
+
+		errRollback := errors.New("rollback")
+		err := mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			_, err := tx.Create(ctx, sid, expired)
+			So(err, ShouldBeNil)
+			So(recorded(), ShouldBeEmpty)
+			return errRollback
+		})
+		So(err, ShouldEqual, errRollback)
+		So(recorded(), ShouldBeEmpty)
+
+		err = mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			_, err := tx.Create(ctx, sid, expired)
+			return err
+		})
+		So(err, ShouldBeNil)
+		So(recorded(), ShouldResemble, []string{"create " + sid})
+	})
+
+	Convey("Test GC reports the expired sessions", t, func() {
+		ctx := context.Background()
+		reset()
+		sid := newSid()
+
+		store, err := mstore.Create(ctx, sid, 1)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		time.Sleep(1100 * time.Millisecond)
+
+		_, err = mstore.clean()
+		So(err, ShouldBeNil)
+		So(recorded(), ShouldResemble, []string{"create " + sid, "expire " + sid})
+	})
+}
@@ -0,0 +1,78 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-session/session"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrIdempotencyDisabled Returned by CreateIdempotent unless Config.EnableIdempotencyKeys is set
+	ErrIdempotencyDisabled = errors.New("gorm session: idempotency keys are not enabled")
+	// ErrIdempotencyConflict Returned by CreateIdempotent if sid was created with another key
+	ErrIdempotencyConflict = errors.New("gorm session: sid was created with another idempotency key")
+)
+
+// idempotencyColumn Used to migrate the idempotency key column onto the session table
+type idempotencyColumn struct {
+	IdempotencyKey *string `gorm:"column:idempotency_key;size:255;"`
+}
+
+// idempotentItem Session row created with an idempotency key
+type idempotentItem struct {
+	SessionItem
+	IdempotencyKey string `gorm:"column:idempotency_key;size:255;"`
+}
+
+// CreateIdempotent Creates the session sid immediately, a repeated call with the same
+// idempotency key, e.g. a double-submitted login, returns the existing session instead
+func (s *ManagerStore) CreateIdempotent(ctx context.Context, sid string, expired int64, idempotencyKey string) (session.Store, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	} else if !s.idempotency {
+		return nil, ErrIdempotencyDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := s.key(sid)
+	item := &idempotentItem{
+		SessionItem: SessionItem{
+			ID:        key,
+			Value:     s.emptyValue(),
+			CreatedAt: time.Now(),
+			ExpiredAt: s.GetExpired(expired),
+		},
+		IdempotencyKey: idempotencyKey,
+	}
+
+	db := s.db
+	if s.separateValues {
+		db = db.Omit("value")
+	}
+	createErr := db.Create(item).Error
+	if createErr == nil {
+		s.missing.remove(key)
+		s.fireCreate(sid)
+
+		return newStore(ctx, s, sid, expired, s.initialValues(ctx)).loaded(&item.SessionItem, false), nil
+	}
+
+	var existing idempotentItem
+	err = s.db.Where("id=?", key).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, createErr
+	} else if err != nil {
+		return nil, err
+	} else if existing.IdempotencyKey != idempotencyKey {
+		return nil, ErrIdempotencyConflict
+	}
+	return s.Update(ctx, sid, expired)
+}
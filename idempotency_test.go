@@ -0,0 +1,47 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateIdempotent(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	var created []string
+	mstore, err := NewManagerStore(Config{
+		TableName:             "session_idempotent",
+		EnableIdempotencyKeys: true,
+		OnCreate:              func(sid string) { created = append(created, sid) },
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test creating a session with an idempotency key", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.CreateIdempotent(ctx, sid, 60, "login-1")
+		So(err, ShouldBeNil)
+		store.Set("user", "foo")
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.CreateIdempotent(ctx, sid, 60, "login-1")
+		So(err, ShouldBeNil)
+		user, ok := store.Get("user")
+		So(ok, ShouldBeTrue)
+		So(user, ShouldEqual, "foo")
+
+		_, err = mstore.CreateIdempotent(ctx, sid, 60, "login-2")
+		So(err, ShouldEqual, ErrIdempotencyConflict)
+
+		// the repeated calls return the existing session without reporting it again
+		So(created, ShouldResemble, []string{sid})
+	})
+}
@@ -0,0 +1,17 @@
+package gorm
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// identifierPattern Plain SQL identifiers, short enough for the derived table and index names
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,47}$`)
+
+// checkIdentifier Returns an error unless name is a plain identifier
+func checkIdentifier(name string) error {
+	if !identifierPattern.MatchString(name) {
+		return fmt.Errorf("gorm session: invalid identifier %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStrictIdentifiers(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+
+	Convey("Test refusing table names that are not plain identifiers", t, func() {
+		_, err := NewManagerStore(Config{TableName: "session; DROP TABLE session", StrictIdentifiers: true}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+		_, err = NewManagerStore(Config{TableName: "session_strict", FallbackTableName: "old`session", StrictIdentifiers: true}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+		_, err = NewManagerStore(Config{TableName: "session_strict", Tables: []string{"brand-session"}, StrictIdentifiers: true}, "sqlite3", dsn)
+		So(err, ShouldNotBeNil)
+
+		mstore, err := NewManagerStore(Config{TableName: "session_strict", Tables: []string{"session_strict_2"}, StrictIdentifiers: true}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		_, err = mstore.Check(WithTable(context.Background(), `session"--`), newSid())
+		So(err, ShouldNotBeNil)
+		_, err = mstore.Check(WithTable(context.Background(), "session_strict_2"), newSid())
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Test values never reach the statements", t, func() {
+		mstore, err := NewManagerStore(Config{TableName: "session_strict"}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		ctx := context.Background()
+		sid := "x'; DROP TABLE session_strict; --" + newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "'); DELETE FROM session_strict; --")
+		So(store.Save(), ShouldBeNil)
+
+		So(mstore.db.HasTable("session_strict"), ShouldBeTrue)
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "'); DELETE FROM session_strict; --")
+
+		deleted, err := mstore.DeleteByIDPrefix(ctx, "x'; DROP")
+		So(err, ShouldBeNil)
+		So(deleted, ShouldEqual, 1)
+		So(mstore.db.HasTable("session_strict"), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,39 @@
+package gorm
+
+import (
+	"context"
+)
+
+// legacyExpiredIndex The name of the expired_at index of the tables created by earlier versions
+const legacyExpiredIndex = "idx_expired_at"
+
+// expiredIndex Returns the name of the expired_at index, index names are global
+// to the schema in some dialects, so they are named per table
+func expiredIndex(table string) string {
+	return "idx_" + table + "_expired_at"
+}
+
+// hasExpiredIndex Reports whether the table has its expired_at index under either name
+func (s *ManagerStore) hasExpiredIndex() bool {
+	dialect := s.db.Dialect()
+	return dialect.HasIndex(s.tableName, expiredIndex(s.tableName)) || dialect.HasIndex(s.tableName, legacyExpiredIndex)
+}
+
+// EnsureIndexes Adds the expired_at index used by GC if it is missing,
+// e.g. during a maintenance window when the store runs with Config.SkipIndexCreation
+func (s *ManagerStore) EnsureIndexes(ctx context.Context) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.hasExpiredIndex() {
+		return nil
+	}
+	return s.db.AddIndex(expiredIndex(s.tableName), "expired_at").Error
+}
@@ -0,0 +1,44 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEnsureIndexes(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_index", SkipIndexCreation: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	other, err := NewManagerStore(Config{TableName: "session_index_other", SkipIndexCreation: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer other.Close()
+
+	Convey("Test adding the indexes on demand", t, func() {
+		ctx := context.Background()
+		for _, store := range []*ManagerStore{mstore, other} {
+			So(store.db.DropTable(store.tableName).Error, ShouldBeNil)
+			So(store.initTable(), ShouldBeNil)
+			So(store.hasExpiredIndex(), ShouldBeFalse)
+		}
+
+		// index names are global in sqlite, each table has its own
+		So(mstore.EnsureIndexes(ctx), ShouldBeNil)
+		So(other.EnsureIndexes(ctx), ShouldBeNil)
+		So(mstore.db.Dialect().HasIndex("session_index", "idx_session_index_expired_at"), ShouldBeTrue)
+		So(other.db.Dialect().HasIndex("session_index_other", "idx_session_index_other_expired_at"), ShouldBeTrue)
+
+		// adding them again is a no-op
+		So(mstore.EnsureIndexes(ctx), ShouldBeNil)
+	})
+}
@@ -0,0 +1,106 @@
+package gorm
+
+import (
+	"context"
+	"time"
+)
+
+// iterateBatchSize Number of sessions ForEachSession reads per query
+const iterateBatchSize = 100
+
+// SessionRecord A session read by ForEachSession
+type SessionRecord struct {
+	SID       string
+	Values    map[string]interface{}
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// ForEachSession Calls fn for every live session of the store's sid prefix in primary key order,
+// reading iterateBatchSize rows per query after the last id seen, so the table is never loaded at once
+// and sessions written during the walk are seen at most once, the FallbackTableName is not walked.
+// The walk stops at the first error of fn, which is returned, or when ctx is done.
+func (s *ManagerStore) ForEachSession(ctx context.Context, fn func(record SessionRecord) error) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var last string
+	for {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		var items []*SessionItem
+		err := s.scoped().Where("id>? AND expired_at>?", last, time.Now()).
+			Order("id").Limit(iterateBatchSize).Find(&items).Error
+		if err != nil {
+			return err
+		} else if len(items) == 0 {
+			return nil
+		}
+		if s.separateValues {
+			if err := s.loadValues(items); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range items {
+			if s.tooOld(item.CreatedAt) {
+				continue
+			}
+
+			values, err := s.parseRow(item.ID, item.Value)
+			if err != nil {
+				return err
+			}
+			err = fn(SessionRecord{
+				SID:       s.sessionID(item.ID),
+				Values:    values,
+				CreatedAt: item.CreatedAt,
+				ExpiresAt: item.ExpiredAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(items) < iterateBatchSize {
+			return nil
+		}
+		last = items[len(items)-1].ID
+	}
+}
+
+// loadValues Reads the values of the items from the values table with one query
+func (s *ManagerStore) loadValues(items []*SessionItem) error {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	var values []valueItem
+	err := s.values().Where("id IN (?)", ids).Find(&values).Error
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]string, len(values))
+	for _, value := range values {
+		byID[value.ID] = value.Value
+	}
+	for _, item := range items {
+		// rows written before SeparateValues keep the value of the session row
+		if value, ok := byID[item.ID]; ok {
+			item.Value = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestForEachSession(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	for _, separate := range []bool{false, true} {
+		table := fmt.Sprintf("session_iterate_%v", separate)
+		mstore, err := NewManagerStore(Config{TableName: table, SeparateValues: separate}, "sqlite3", dsn)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		defer mstore.Close()
+
+		Convey(fmt.Sprintf("Test walking the sessions in batches (separate values %v)", separate), t, func() {
+			ctx := context.Background()
+			mstore.db.Delete(nil)
+
+			sids := make(map[string]int)
+			for i := 0; i < iterateBatchSize+5; i++ {
+				sid := newSid()
+				sids[sid] = i
+				store, err := mstore.Create(ctx, sid, 60)
+				So(err, ShouldBeNil)
+				store.Set("n", i)
+				So(store.Save(), ShouldBeNil)
+			}
+			store, err := mstore.Create(ctx, newSid(), -60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+
+			seen := make(map[string]bool)
+			var last string
+			err = mstore.ForEachSession(ctx, func(record SessionRecord) error {
+				So(record.SID, ShouldBeGreaterThan, last)
+				last = record.SID
+				seen[record.SID] = true
+
+				n, ok := sids[record.SID]
+				So(ok, ShouldBeTrue)
+				So(record.Values["n"], ShouldEqual, n)
+				So(record.ExpiresAt.After(record.CreatedAt), ShouldBeTrue)
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(seen, ShouldHaveLength, len(sids))
+
+			stop := errors.New("stop")
+			calls := 0
+			err = mstore.ForEachSession(ctx, func(SessionRecord) error {
+				calls++
+				return stop
+			})
+			So(err, ShouldEqual, stop)
+			So(calls, ShouldEqual, 1)
+
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			err = mstore.ForEachSession(canceled, func(SessionRecord) error { return nil })
+			So(err, ShouldEqual, context.Canceled)
+		})
+	}
+}
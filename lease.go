@@ -0,0 +1,87 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLeasesDisabled Returned by the lease operations unless Config.EnableLeases is set
+var ErrLeasesDisabled = errors.New("gorm session: leases are not enabled")
+
+// leaseColumns Used to migrate the lease columns onto the session table
+type leaseColumns struct {
+	LeaseOwner     *string    `gorm:"column:lease_owner;size:255;"`
+	LeaseExpiresAt *time.Time `gorm:"column:lease_expires_at;"`
+}
+
+// AcquireLease Claims exclusive processing of the session sid for owner during ttl,
+// e.g. by a background job. It reports false if another owner holds an unexpired
+// lease or the session does not exist. The current owner renews the lease by calling it again
+// within ttl, the lease of an owner that stopped renewing, e.g. a crashed pod, runs out and GC clears it.
+func (s *ManagerStore) AcquireLease(ctx context.Context, sid, owner string, ttl time.Duration) (bool, error) {
+	if s.isClosed() {
+		return false, ErrStoreClosed
+	} else if !s.leases {
+		return false, ErrLeasesDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	key := s.key(sid)
+	result := s.db.Where("id=? AND (lease_owner IS NULL OR lease_owner=? OR lease_expires_at<=?)", key, owner, now).
+		Updates(map[string]interface{}{
+			"lease_owner":      owner,
+			"lease_expires_at": now.Add(ttl),
+		})
+	if err := result.Error; err != nil {
+		return false, err
+	} else if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// some drivers count unchanged rows as unaffected, e.g. a renewal within the same second
+	var count int
+	err = s.db.Where("id=? AND lease_owner=? AND lease_expires_at>?", key, owner, now).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ReleaseLease Gives up the lease of owner on the session sid
+func (s *ManagerStore) ReleaseLease(ctx context.Context, sid, owner string) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	} else if !s.leases {
+		return ErrLeasesDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Where("id=? AND lease_owner=?", s.key(sid), owner).
+		Updates(map[string]interface{}{
+			"lease_owner":      nil,
+			"lease_expires_at": nil,
+		})
+	return result.Error
+}
+
+// releaseStaleLeases Clears the leases that ran out without being released or renewed
+func (s *ManagerStore) releaseStaleLeases() error {
+	result := s.scoped().Where("lease_owner IS NOT NULL AND lease_expires_at<=?", time.Now()).
+		Updates(map[string]interface{}{
+			"lease_owner":      nil,
+			"lease_expires_at": nil,
+		})
+	return result.Error
+}
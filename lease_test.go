@@ -0,0 +1,65 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLeases(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_lease", EnableLeases: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test exclusive leases on a session", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		ok, err := mstore.AcquireLease(ctx, sid, "worker-1", time.Minute)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		ok, err = mstore.AcquireLease(ctx, sid, "worker-1", time.Minute)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		ok, err = mstore.AcquireLease(ctx, sid, "worker-2", time.Minute)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		ok, err = mstore.AcquireLease(ctx, sid, "worker-1", time.Minute)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		So(mstore.ReleaseLease(ctx, sid, "worker-1"), ShouldBeNil)
+		ok, err = mstore.AcquireLease(ctx, sid, "worker-2", -time.Second)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		// the lease of worker-2 has already run out
+		ok, err = mstore.AcquireLease(ctx, sid, "worker-1", time.Minute)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		// GC clears the lease of an owner that stopped renewing it
+		ok, err = mstore.AcquireLease(ctx, sid, "worker-1", -time.Second)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		mstore.clean()
+		var count int
+		So(mstore.db.Where("id=? AND lease_owner IS NOT NULL", sid).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,87 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrLineageDisabled Returned by Lineage unless Config.EnableLineage is set
+var ErrLineageDisabled = errors.New("gorm session: lineage is not enabled")
+
+// maxLineage Bounds the walk of a rotation chain
+const maxLineage = 10000
+
+// lineageItem Rotation of a session by Refresh
+type lineageItem struct {
+	ID          uint      `gorm:"column:id;primary_key;"`
+	OldID       string    `gorm:"column:old_id;size:255;"`
+	NewID       string    `gorm:"column:new_id;size:255;"`
+	RefreshedAt time.Time `gorm:"column:refreshed_at;"`
+}
+
+// lineageTable Returns the name of the lineage table of a session table
+func lineageTable(tableName string) string {
+	return tableName + "_lineage"
+}
+
+// lineage Returns the db of the lineage table
+func (s *ManagerStore) lineage() *gorm.DB {
+	return s.db.Table(lineageTable(s.tableName))
+}
+
+func (s *ManagerStore) initLineageTable() error {
+	table := lineageTable(s.tableName)
+	if s.db.HasTable(table) {
+		return nil
+	}
+
+	err := s.lineage().CreateTable(&lineageItem{}).Error
+	if err != nil && !s.db.HasTable(table) {
+		return err
+	}
+	s.lineage().AddIndex("idx_"+table+"_new_id", "new_id")
+	return nil
+}
+
+func (s *ManagerStore) cleanLineage() error {
+	return s.lineage().Where("refreshed_at<=?", time.Now().Add(-s.lineageRetention)).Delete(nil).Error
+}
+
+// Lineage Returns the rotation chain of the session sid, from the first sid
+// up to sid itself, following the Refresh calls recorded with Config.EnableLineage
+func (s *ManagerStore) Lineage(ctx context.Context, sid string) ([]string, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	} else if !s.lineageEnabled {
+		return nil, ErrLineageDisabled
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []string{sid}
+	seen := map[string]bool{s.key(sid): true}
+	key := s.key(sid)
+	for len(chain) < maxLineage {
+		var item lineageItem
+		err := s.lineage().Where("new_id=?", key).Order("refreshed_at DESC").First(&item).Error
+		if err == gorm.ErrRecordNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		} else if seen[item.OldID] {
+			break
+		}
+
+		seen[item.OldID] = true
+		key = item.OldID
+		chain = append([]string{s.sessionID(key)}, chain...)
+	}
+	return chain, nil
+}
@@ -0,0 +1,43 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLineage(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_lineage", EnableLineage: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test following the rotation chain of a session", t, func() {
+		ctx := context.Background()
+		sids := []string{newSid(), newSid(), newSid()}
+		defer mstore.Delete(ctx, sids[2])
+
+		store, err := mstore.Create(ctx, sids[0], 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		_, err = mstore.Refresh(ctx, sids[0], sids[1], 60)
+		So(err, ShouldBeNil)
+		_, err = mstore.Refresh(ctx, sids[1], sids[2], 60)
+		So(err, ShouldBeNil)
+
+		chain, err := mstore.Lineage(ctx, sids[2])
+		So(err, ShouldBeNil)
+		So(chain, ShouldResemble, sids)
+
+		chain, err = mstore.Lineage(ctx, sids[0])
+		So(err, ShouldBeNil)
+		So(chain, ShouldResemble, sids[:1])
+	})
+}
@@ -0,0 +1,93 @@
+package gorm
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// schemaLockTimeout Limits the wait for the schema lock of another instance
+const schemaLockTimeout = time.Minute
+
+// lockKey Returns the key of the advisory lock of the table for purpose within scope,
+// e.g. a sid prefix, as the 64-bit key of postgres and as the name of mysql,
+// which is limited to 64 characters
+func (s *ManagerStore) lockKey(purpose, scope string) (int64, string) {
+	name := s.tableName + ":" + purpose
+	if scope != "" {
+		name += ":" + scope
+	}
+	sum := sha256.Sum256([]byte(name))
+	key := int64(binary.BigEndian.Uint64(sum[:8]))
+	return key, fmt.Sprintf("gorm_session_%s_%x", purpose, sum[:8])
+}
+
+// advisoryLock An advisory lock held on a dedicated connection,
+// the database releases it when the connection is lost
+type advisoryLock struct {
+	conn   *sql.Conn
+	unlock string
+	args   []interface{}
+}
+
+// held Reports whether the lock is still held, i.e. its connection is alive
+func (l *advisoryLock) held(ctx context.Context) bool {
+	return l.conn == nil || l.conn.PingContext(ctx) == nil
+}
+
+// release Releases the lock and its connection
+func (l *advisoryLock) release() {
+	if l.conn == nil {
+		return
+	}
+	l.conn.ExecContext(context.Background(), l.unlock, l.args...)
+	l.conn.Close()
+}
+
+// lock Takes the advisory lock of the table for purpose within scope on a dedicated connection,
+// waiting up to timeout for another holder, or not at all with a zero timeout.
+// It reports false if the lock is held elsewhere, dialects without
+// advisory locks (all but mysql and postgres) always acquire it.
+func (s *ManagerStore) lock(ctx context.Context, purpose, scope string, timeout time.Duration) (*advisoryLock, bool, error) {
+	dialect := s.db.Dialect().GetName()
+	if dialect != "mysql" && dialect != "postgres" {
+		return &advisoryLock{}, true, nil
+	}
+
+	conn, err := s.sqlDB().Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key, name := s.lockKey(purpose, scope)
+	var ok bool
+	var unlock string
+	var args []interface{}
+	if dialect == "mysql" {
+		var got sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(timeout/time.Second)).Scan(&got)
+		ok = got.Valid && got.Int64 == 1
+		unlock, args = "SELECT RELEASE_LOCK(?)", []interface{}{name}
+	} else if timeout > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, err = conn.ExecContext(waitCtx, "SELECT pg_advisory_lock($1)", key)
+		cancel()
+		ok = err == nil
+		if waitCtx.Err() == context.DeadlineExceeded {
+			err = nil
+		}
+		unlock, args = "SELECT pg_advisory_unlock($1)", []interface{}{key}
+	} else {
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok)
+		unlock, args = "SELECT pg_advisory_unlock($1)", []interface{}{key}
+	}
+	if err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+
+	return &advisoryLock{conn: conn, unlock: unlock, args: args}, true, nil
+}
@@ -0,0 +1,137 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConcurrentBootstrap(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm_bootstrap.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer db.Close()
+	db.DropTableIfExists("session_bootstrap", "session_bootstrap_challenges")
+
+	Convey("Test replicas bootstrapping the schema at the same time", t, func() {
+		cfg := Config{TableName: "session_bootstrap", Signer: SignerFunc(hmacSign), EnableChallenges: true, ChallengeKey: []byte("challenge"), LockSchema: true}
+		stores := make([]*ManagerStore, 4)
+		errs := make([]error, len(stores))
+		var wg sync.WaitGroup
+		for i := range stores {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				stores[i], errs[i] = NewManagerStore(cfg, "sqlite3", os.TempDir()+"/gorm_bootstrap.db")
+			}(i)
+		}
+		wg.Wait()
+
+		for i, mstore := range stores {
+			So(errs[i], ShouldBeNil)
+			So(mstore.verifySchema(), ShouldBeNil)
+			So(mstore.missingColumn(mstore.challenges(), &challengeItem{}), ShouldBeEmpty)
+			mstore.Close()
+		}
+
+		lock, ok, err := stores[0].lock(context.Background(), "schema", "", 0)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		lock.release()
+	})
+}
+
+func TestCoordinateGC(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_coordinate_gc", CoordinateGC: true, NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test the GC leader takes the lead on its first run", t, func() {
+		ctx := context.Background()
+		status, err := mstore.GCStatus(ctx)
+		So(err, ShouldBeNil)
+		So(status.Leader, ShouldBeFalse)
+
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, -60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		So(mstore.GC(ctx), ShouldBeNil)
+		status, err = mstore.GCStatus(ctx)
+		So(err, ShouldBeNil)
+		So(status.Leader, ShouldBeTrue)
+		So(status.Runs, ShouldEqual, 1)
+		So(status.Backlog, ShouldEqual, 0)
+	})
+
+	Convey("Test the GC leader takes the lead again once its lock is lost", t, func() {
+		conn, err := mstore.db.DB().Conn(context.Background())
+		So(err, ShouldBeNil)
+		So(conn.Close(), ShouldBeNil)
+
+		lost := &advisoryLock{conn: conn}
+		mstore.gcMu.Lock()
+		mstore.gcLock = lost
+		mstore.gcMu.Unlock()
+
+		So(mstore.leadGC(), ShouldBeTrue)
+		So(mstore.gcLock, ShouldNotEqual, lost)
+		So(mstore.gcLock.held(context.Background()), ShouldBeTrue)
+	})
+}
+
+func TestCoordinateGCSIDPrefix(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	stores := make([]*ManagerStore, 2)
+	for i, prefix := range []string{"app_1:", "app_2:"} {
+		mstore, err := NewManagerStore(Config{TableName: "session_coordinate_shared", SIDPrefix: prefix, CoordinateGC: true, NoBackground: true}, "sqlite3", dsn)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		defer mstore.Close()
+		stores[i] = mstore
+	}
+
+	Convey("Test the stores of each sid prefix lead the GC of their own sessions", t, func() {
+		gcKey, gcName := stores[0].lockKey("gc", stores[0].idPrefix)
+		otherKey, otherName := stores[1].lockKey("gc", stores[1].idPrefix)
+		So(gcKey, ShouldNotEqual, otherKey)
+		So(gcName, ShouldNotEqual, otherName)
+		So(len(gcName), ShouldBeLessThanOrEqualTo, 64)
+
+		schemaKey, _ := stores[0].lockKey("schema", "")
+		otherKey, _ = stores[1].lockKey("schema", "")
+		So(schemaKey, ShouldEqual, otherKey)
+
+		ctx := context.Background()
+		sids := []string{newSid(), newSid()}
+		for i, mstore := range stores {
+			store, err := mstore.Create(ctx, sids[i], -60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		for i, mstore := range stores {
+			So(mstore.GC(ctx), ShouldBeNil)
+			status, err := mstore.GCStatus(ctx)
+			So(err, ShouldBeNil)
+			So(status.Leader, ShouldBeTrue)
+
+			var count int
+			So(mstore.db.Where("id=?", mstore.key(sids[i])).Count(&count).Error, ShouldBeNil)
+			So(count, ShouldEqual, 0)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package gorm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Logger A structured logger of the store operations, *slog.Logger implements it,
+// the arguments are alternating keys and values: op, table, sid_hash, duration, rows and error
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// sidHash Returns a short hash of sid to correlate the log entries
+// of a session without logging the sid itself
+func sidHash(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:8])
+}
+
+// logOp Logs an operation on sid that started at start and failed with *err,
+// at debug level if it succeeded, and reports it to the metrics, a database
+// error is wrapped in a DBError of its class first
+func (s *ManagerStore) logOp(ctx context.Context, op, sid string, start time.Time, err *error) {
+	*err = classify(*err)
+	s.observeOp(ctx, op, sid, start, *err)
+	if s.logger == nil {
+		return
+	} else if ctx == nil {
+		ctx = context.Background()
+	}
+
+	args := []interface{}{"op", op, "table", s.tableName, "sid_hash", sidHash(sid), "duration", time.Since(start)}
+	if *err != nil {
+		s.logger.ErrorContext(ctx, "gorm session: operation failed",
+			append(args, "error", *err, "error_class", ClassifyError(*err))...)
+		return
+	}
+	s.logger.DebugContext(ctx, "gorm session: operation", args...)
+}
+
+// logGC Logs a GC run that started at start and deleted rows sessions
+func (s *ManagerStore) logGC(start time.Time, rows int64) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.InfoContext(context.Background(), "gorm session: gc",
+		"op", "gc", "table", s.tableName, "duration", time.Since(start), "rows", rows)
+}
+
+// internalLogger Returns the logger of the errors and diagnostics of the store,
+// the output if no Logger is configured
+func (s *ManagerStore) internalLogger() Logger {
+	if s.logger != nil {
+		return s.logger
+	} else if s.output != nil {
+		return s.output
+	}
+	return nil
+}
+
+// gormLogger Routes the log of gorm to the Logger of the store, the statements
+// at debug level and the errors at error level, leaving out the query arguments
+// since they carry the session values
+type gormLogger struct {
+	logger Logger
+	table  string
+}
+
+func (l gormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		return
+	}
+
+	ctx := context.Background()
+	if values[0] == "sql" && len(values) >= 6 {
+		vars, _ := values[4].([]interface{})
+		l.logger.DebugContext(ctx, "gorm session: sql", "table", l.table, "source", values[1],
+			"duration", values[2], "sql", values[3], "vars", len(vars), "rows", values[5])
+		return
+	}
+	l.logger.ErrorContext(ctx, "gorm session: sql error", "table", l.table, "source", values[1],
+		"error", fmt.Sprint(values[2:]...))
+}
+
+// setSQLLogger Routes the log of gorm on db to the Logger of the store if one is configured
+func (s *ManagerStore) setSQLLogger(db *gorm.DB) {
+	if s.logger != nil {
+		db.SetLogger(gormLogger{logger: s.logger, table: s.tableName})
+	}
+}
+
+// NewWriterLogger Returns a Logger that writes a line of the time, level, message
+// and key=value pairs per record to w, the debug records only if debug is set
+func NewWriterLogger(w io.Writer, debug bool) Logger {
+	return &writerLogger{w: w, debug: debug}
+}
+
+// newOutputLogger Returns the logger of the errors and diagnostics written to the output
+// of the store, nil if the output is discarded
+func newOutputLogger(w io.Writer) Logger {
+	if w == nil {
+		return nil
+	}
+	return &outputLogger{w: w}
+}
+
+// outputTags The tags of the output lines of the messages that are not tagged by their level
+var outputTags = map[string]string{
+	poolMessage: "POOL",
+}
+
+// outputLogger A Logger writing the lines of the output the store wrote before
+// the Logger, e.g. "[GORM-SESSION-ERROR]: <error>", without the debug records
+type outputLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *outputLogger) DebugContext(context.Context, string, ...interface{}) {}
+
+func (l *outputLogger) InfoContext(_ context.Context, msg string, args ...interface{}) {
+	l.write("INFO", msg, args)
+}
+
+func (l *outputLogger) WarnContext(_ context.Context, msg string, args ...interface{}) {
+	l.write("WARN", msg, args)
+}
+
+func (l *outputLogger) ErrorContext(_ context.Context, msg string, args ...interface{}) {
+	l.write("ERROR", msg, args)
+}
+
+// write Writes a line of the error of args, or of msg unless it is tagged,
+// followed by the other key=value pairs, the table is left out as it always was
+func (l *outputLogger) write(level, msg string, args []interface{}) {
+	var parts []string
+	tag, tagged := outputTags[msg]
+	if !tagged {
+		tag = level
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "error" {
+			parts = append(parts, fmt.Sprint(args[i+1]))
+			tagged = true
+		}
+	}
+	if !tagged {
+		parts = append(parts, msg)
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] != "error" && args[i] != "table" {
+			parts = append(parts, fmt.Sprintf("%v=%v", args[i], args[i+1]))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write([]byte("[GORM-SESSION-" + tag + "]: " + strings.Join(parts, " ") + "\n"))
+}
+
+// writerLogger A Logger writing plain lines to an io.Writer
+type writerLogger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	debug bool
+}
+
+func (l *writerLogger) DebugContext(_ context.Context, msg string, args ...interface{}) {
+	if l.debug {
+		l.write("DEBUG", msg, args)
+	}
+}
+
+func (l *writerLogger) InfoContext(_ context.Context, msg string, args ...interface{}) {
+	l.write("INFO", msg, args)
+}
+
+func (l *writerLogger) WarnContext(_ context.Context, msg string, args ...interface{}) {
+	l.write("WARN", msg, args)
+}
+
+func (l *writerLogger) ErrorContext(_ context.Context, msg string, args ...interface{}) {
+	l.write("ERROR", msg, args)
+}
+
+func (l *writerLogger) write(level, msg string, args []interface{}) {
+	var buf strings.Builder
+	buf.WriteString(time.Now().Format(time.RFC3339))
+	buf.WriteString(" " + level + " " + msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		value := fmt.Sprint(args[i+1])
+		if value == "" || strings.ContainsAny(value, " =\"\n") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&buf, " %v=%s", args[i], value)
+	}
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write([]byte(buf.String()))
+}
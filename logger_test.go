@@ -0,0 +1,141 @@
+//go:build go1.21
+// +build go1.21
+
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var _ Logger = (*slog.Logger)(nil)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_slog", Logger: logger, NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test structured logs of the store operations", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(mstore.GC(ctx), ShouldBeNil)
+
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]interface{}
+			So(json.Unmarshal([]byte(line), &entry), ShouldBeNil)
+			entries = append(entries, entry)
+		}
+		So(len(entries), ShouldEqual, 3)
+		So(entries[0]["op"], ShouldEqual, "create")
+		So(entries[0]["table"], ShouldEqual, "session_slog")
+		So(entries[0]["sid_hash"], ShouldEqual, sidHash(sid))
+		So(entries[1]["op"], ShouldEqual, "save")
+		So(entries[2]["op"], ShouldEqual, "gc")
+		So(entries[2]["level"], ShouldEqual, "INFO")
+		So(entries[2]["table"], ShouldEqual, "session_slog")
+		So(strings.Contains(buf.String(), sid), ShouldBeFalse)
+	})
+}
+
+func TestWriterLogger(t *testing.T) {
+	Convey("Test the plain line logger of the output", t, func() {
+		var buf bytes.Buffer
+		logger := NewWriterLogger(&buf, false)
+		logger.DebugContext(context.Background(), "gorm session: operation", "op", "save")
+		So(buf.Len(), ShouldEqual, 0)
+
+		logger.ErrorContext(context.Background(), "gorm session: error", "error", "no such table: session", "rows", 0)
+		line := buf.String()
+		So(line, ShouldEndWith, " ERROR gorm session: error error=\"no such table: session\" rows=0\n")
+
+		buf.Reset()
+		NewWriterLogger(&buf, true).DebugContext(context.Background(), "gorm session: operation", "op", "save")
+		So(buf.String(), ShouldContainSubstring, " DEBUG gorm session: operation op=save\n")
+
+		// the store writes its GC errors as lines of the output
+		db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+		So(err, ShouldBeNil)
+		buf.Reset()
+		mstore, err := newManagerStore(db, Config{TableName: "session_writer_logger", NoBackground: true}, &buf)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+		So(mstore.db.DropTable("session_writer_logger").Error, ShouldBeNil)
+		mstore.runGC()
+		So(buf.String(), ShouldStartWith, "[GORM-SESSION-ERROR]: ")
+		So(buf.String(), ShouldContainSubstring, "session_writer_logger")
+	})
+
+	Convey("Test the lines of the output keep the format of earlier versions", t, func() {
+		var buf bytes.Buffer
+		logger := newOutputLogger(&buf)
+		ctx := context.Background()
+		logger.DebugContext(ctx, "gorm session: operation", "op", "save")
+		So(buf.Len(), ShouldEqual, 0)
+
+		logger.ErrorContext(ctx, "gorm session: error", "error", "no such table: session")
+		logger.WarnContext(ctx, "gorm session: expiry mismatch", "op", "check_expiry", "warning", "expires early")
+		logger.InfoContext(ctx, poolMessage, "table", "session", "open", 1, "idle", 0)
+		So(buf.String(), ShouldEqual, "[GORM-SESSION-ERROR]: no such table: session\n"+
+			"[GORM-SESSION-WARN]: gorm session: expiry mismatch op=check_expiry warning=expires early\n"+
+			"[GORM-SESSION-POOL]: open=1 idle=0\n")
+
+		buf.Reset()
+		NewWriterLogger(&buf, false).WarnContext(ctx, "gorm session: expiry mismatch", "op", "check_expiry")
+		So(buf.String(), ShouldContainSubstring, " WARN gorm session: expiry mismatch op=check_expiry\n")
+	})
+}
+
+func TestSQLLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriterLogger(&buf, true)
+
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_sql_logger", Logger: logger, Debug: true, NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test routing the SQL log of gorm to the Logger", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+		buf.Reset()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "secret_value")
+		So(store.Save(), ShouldBeNil)
+
+		So(buf.String(), ShouldContainSubstring, " DEBUG gorm session: sql table=session_sql_logger ")
+		So(buf.String(), ShouldNotContainSubstring, "secret_value")
+		So(buf.String(), ShouldNotContainSubstring, sid)
+
+		// the queries of a context bound copy are routed as well
+		buf.Reset()
+		exists, err := mstore.withContext(ctx).Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+		So(buf.String(), ShouldContainSubstring, " DEBUG gorm session: sql table=session_sql_logger ")
+	})
+}
@@ -0,0 +1,67 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// OtherLabel The label of the operations whose label is not listed in Config.MetricsLabels
+const OtherLabel = "other"
+
+// MetricsSink Receives the metrics of the store, e.g. to export them as
+// Prometheus counters and histograms to alert on the health of the store
+type MetricsSink interface {
+	// ObserveOperation Records an operation ("check", "create", "update", "refresh",
+	// "save" or "delete") on table, its label from Config.MetricsLabel ("" without it),
+	// its duration and its error, nil on success, ClassifyError of the error and
+	// the label are labels of bounded cardinality
+	ObserveOperation(table, label, op string, duration time.Duration, err error)
+	// ObserveGC Records a GC run on table, its duration, the deleted sessions
+	// and its first error, nil on success
+	ObserveGC(table string, duration time.Duration, deleted int64, err error)
+	// ObservePool Records the statistics of the connection pool of the store of table
+	// after a GC run with Config.LogPoolStats, the open, in use and idle connections are
+	// gauges, the wait and closed counts are totals since the pool was opened
+	ObservePool(table string, stats sql.DBStats)
+}
+
+// observeOp Reports an operation on sid that started at start and failed with err to the metrics
+func (s *ManagerStore) observeOp(ctx context.Context, op, sid string, start time.Time, err error) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.ObserveOperation(s.tableName, s.metricsLabel(ctx, sid), op, time.Since(start), err)
+}
+
+// metricsLabel Returns the label of an operation on sid, OtherLabel
+// for a label that is not listed in Config.MetricsLabels
+func (s *ManagerStore) metricsLabel(ctx context.Context, sid string) string {
+	if s.labelFunc == nil {
+		return ""
+	} else if ctx == nil {
+		ctx = context.Background()
+	}
+
+	label := s.labelFunc(ctx, sid)
+	if !s.labels[label] {
+		return OtherLabel
+	}
+	return label
+}
+
+// observePool Reports the statistics of the connection pool to the metrics
+func (s *ManagerStore) observePool(stats sql.DBStats) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.ObservePool(s.tableName, stats)
+}
+
+// observeGC Reports a GC run that started at start to the metrics
+func (s *ManagerStore) observeGC(start time.Time, deleted int64, err error) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.ObserveGC(s.tableName, time.Since(start), deleted, err)
+}
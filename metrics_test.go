@@ -0,0 +1,128 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testMetrics struct {
+	mu     sync.Mutex
+	ops    map[string]int
+	errors map[string]int
+	gc     int
+	pool   []sql.DBStats
+}
+
+func (m *testMetrics) ObserveOperation(table, label, op string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops[table+"."+op]++
+	if label != "" {
+		m.ops[label+"."+op]++
+	}
+	if err != nil {
+		m.errors[op]++
+	}
+}
+
+func (m *testMetrics) ObserveGC(table string, duration time.Duration, deleted int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gc++
+}
+
+func (m *testMetrics) ObservePool(table string, stats sql.DBStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pool = append(m.pool, stats)
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := &testMetrics{ops: make(map[string]int), errors: make(map[string]int)}
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_metrics", Metrics: metrics, LogPoolStats: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test reporting the operations to the metrics", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		jsonMarshal = func(interface{}) ([]byte, error) { return nil, errors.New("marshal failed") }
+		So(store.Save(), ShouldNotBeNil)
+		jsonMarshal = json.Marshal
+		mstore.runGC()
+
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		So(metrics.ops["session_metrics.create"], ShouldEqual, 1)
+		So(metrics.ops["session_metrics.save"], ShouldEqual, 2)
+		So(metrics.ops["session_metrics.update"], ShouldEqual, 1)
+		So(metrics.ops["session_metrics.delete"], ShouldEqual, 1)
+		So(metrics.errors["save"], ShouldEqual, 1)
+		So(metrics.gc, ShouldEqual, 1)
+		So(metrics.pool, ShouldHaveLength, 1)
+	})
+}
+
+type tenantKey struct{}
+
+func TestMetricsLabel(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	_, err := NewManagerStore(Config{
+		TableName:    "session_metrics_label",
+		MetricsLabel: func(context.Context, string) string { return "" },
+	}, "sqlite3", dsn)
+	if err == nil {
+		t.Error("a MetricsLabel without MetricsLabels must be refused")
+		return
+	}
+
+	metrics := &testMetrics{ops: make(map[string]int), errors: make(map[string]int)}
+	mstore, err := NewManagerStore(Config{
+		TableName: "session_metrics_label",
+		Metrics:   metrics,
+		MetricsLabel: func(ctx context.Context, sid string) string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return tenant
+		},
+		MetricsLabels: []string{"acme"},
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test labeling the operations from their context within a bounded set", t, func() {
+		for _, tenant := range []string{"acme", "acme", "globex", "initech"} {
+			ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+			_, err := mstore.Check(ctx, newSid())
+			So(err, ShouldBeNil)
+		}
+
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		So(metrics.ops["acme.check"], ShouldEqual, 2)
+		So(metrics.ops[OtherLabel+".check"], ShouldEqual, 2)
+		So(metrics.ops, ShouldNotContainKey, "globex.check")
+	})
+}
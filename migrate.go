@@ -0,0 +1,144 @@
+package gorm
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+)
+
+// MigrateValues Rewrites the values of the live sessions of the store's sid prefix that are not in the
+// format the store writes, e.g. after enabling or changing compression, enabling encryption or rotating
+// the encryption key, reading batchSize rows per query in primary key order, and returns the number of
+// rewritten rows. Every value records its own format with a leading marker, '!' and the key id when
+// encrypted, then '~' and the compressor tag or '^' and the dictionary id when compressed, so reads
+// fall back to the format a value was written with and no separate format column is kept. It can run
+// in a goroutine while the store serves requests: a value saved meanwhile is kept, undecodable values
+// are skipped.
+func (s *ManagerStore) MigrateValues(ctx context.Context, batchSize int) (int64, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if batchSize <= 0 {
+		batchSize = iterateBatchSize
+	}
+
+	var last string
+	var rewritten int64
+	for {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return rewritten, err
+			}
+		}
+
+		var items []*SessionItem
+		err := s.scoped().Where("id>? AND expired_at>?", last, time.Now()).
+			Order("id").Limit(batchSize).Find(&items).Error
+		if err != nil {
+			return rewritten, err
+		} else if len(items) == 0 {
+			return rewritten, nil
+		}
+		if s.separateValues {
+			if err := s.loadValues(items); err != nil {
+				return rewritten, err
+			}
+		}
+
+		for _, item := range items {
+			if !s.outdated(item.Value) {
+				continue
+			}
+
+			ok, err := s.rewriteValue(item.ID, item.Value)
+			if err != nil {
+				return rewritten, err
+			} else if ok {
+				rewritten++
+			}
+		}
+		if len(items) < batchSize {
+			return rewritten, nil
+		}
+		last = items[len(items)-1].ID
+	}
+}
+
+// outdated Reports whether a stored value is not in the format the store writes
+func (s *ManagerStore) outdated(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	encrypted := value[0] == encryptedMarker
+	if s.keyring == nil || s.deferred(featureEncryption) {
+		if encrypted {
+			return true
+		}
+	} else if !encrypted || len(value) < encryptedHeader || value[3:encryptedHeader] != s.keyring.id {
+		return true
+	}
+
+	value, err := s.decryptValue(value)
+	if err != nil {
+		return false
+	}
+
+	compressed := value != "" && (value[0] == compressedMarker || value[0] == dictionaryMarker)
+	id, _ := s.currentDictionary()
+	switch {
+	case s.deferred(featureCompression) || (s.compression == 0 && id == ""):
+		return compressed
+	case !compressed:
+		return len(value) >= s.compressAbove
+	case id != "":
+		return value[0] != dictionaryMarker || len(value) < 1+dictionaryIDSize || value[1:1+dictionaryIDSize] != id
+	}
+	return value[0] != compressedMarker || len(value) < 3 || value[1:3] != hex.EncodeToString([]byte{s.compression})
+}
+
+// rewriteValue Writes the value of the row key in the current format unless it changed
+// since it was read as old, and reports whether it was rewritten
+func (s *ManagerStore) rewriteValue(key, old string) (bool, error) {
+	values, _, err := s.decodeValues(old)
+	if err != nil {
+		return false, nil
+	}
+
+	buf, err := jsonMarshal(values)
+	if err != nil {
+		s.codecFailed(codecMarshal, s.sessionID(key), err)
+		return false, err
+	}
+	value, err := s.encodeValue(string(buf))
+	if err != nil {
+		return false, err
+	}
+
+	fields := make(map[string]interface{})
+	if s.signer != nil {
+		signature, err := s.signer.Sign(key, []byte(value))
+		if err != nil {
+			return false, err
+		}
+		fields["signature"] = signature
+	}
+
+	if s.separateValues {
+		result := s.values().Where("id=? AND value=?", key, old).Update("value", value)
+		if err := result.Error; err != nil || result.RowsAffected == 0 || len(fields) == 0 {
+			return result.RowsAffected > 0, err
+		}
+		return true, s.db.Where("id=?", key).Updates(fields).Error
+	}
+
+	fields["value"] = value
+	result := s.db.Where("id=? AND value=?", key, old).Updates(fields)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,101 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMigrateValues(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 16)
+
+	dsn := os.TempDir() + "/gorm.db"
+	old, err := NewManagerStore(Config{TableName: "session_migrate", EncryptionKey: oldKey}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer old.Close()
+
+	mstore, err := NewManagerStore(Config{
+		TableName:      "session_migrate",
+		EncryptionKey:  newKey,
+		DecryptionKeys: [][]byte{oldKey},
+		Compression:    GzipCompression,
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test rewriting the values into the current format", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+
+		var sids []string
+		for i := 0; i < 5; i++ {
+			sid := newSid()
+			sids = append(sids, sid)
+			store, err := old.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("email", "foo@example.com")
+			So(store.Save(), ShouldBeNil)
+		}
+
+		current, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		current.Set("email", "bar@example.com")
+		So(current.Save(), ShouldBeNil)
+
+		n, err := mstore.MigrateValues(ctx, 2)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 5)
+
+		var items []SessionItem
+		So(mstore.db.Find(&items).Error, ShouldBeNil)
+		So(items, ShouldHaveLength, 6)
+		for _, item := range items {
+			So(mstore.outdated(item.Value), ShouldBeFalse)
+		}
+
+		for _, sid := range sids {
+			store, err := mstore.Update(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			email, ok := store.Get("email")
+			So(ok, ShouldBeTrue)
+			So(email, ShouldEqual, "foo@example.com")
+		}
+
+		n, err = mstore.MigrateValues(ctx, 0)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+	})
+
+	Convey("Test a value saved meanwhile is kept", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		store, err := old.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("email", "foo@example.com")
+		So(store.Save(), ShouldBeNil)
+
+		var item SessionItem
+		So(mstore.db.Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		store.Set("email", "bar@example.com")
+		So(store.Save(), ShouldBeNil)
+
+		ok, err := mstore.rewriteValue(item.ID, item.Value)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		store2, err := mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		email, _ := store2.Get("email")
+		So(email, ShouldEqual, "bar@example.com")
+	})
+}
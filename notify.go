@@ -0,0 +1,62 @@
+package gorm
+
+import (
+	"context"
+	"time"
+)
+
+// notifiedAtColumn Used to migrate the notified_at column onto the session table
+type notifiedAtColumn struct {
+	NotifiedAt *time.Time `gorm:"column:notified_at;"`
+}
+
+// NotifyExpiring Calls OnExpiring for every session that expires within
+// ExpiryNoticeWindow and has not been notified yet, returns the number of notified sessions.
+// Each session is claimed before the callback fires, so several instances
+// scanning the same table notify a session only once.
+func (s *ManagerStore) NotifyExpiring(ctx context.Context) (int, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	} else if s.onExpiring == nil {
+		return 0, nil
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var items []SessionItem
+	err = s.scoped().Select("id, expired_at").
+		Where("expired_at>? AND expired_at<=? AND notified_at IS NULL", now, now.Add(s.noticeWindow)).
+		Find(&items).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var notified int
+	for _, item := range items {
+		result := s.db.Where("id=? AND notified_at IS NULL", item.ID).Update("notified_at", now)
+		if err := result.Error; err != nil {
+			return notified, err
+		} else if result.RowsAffected == 0 {
+			continue
+		}
+
+		s.onExpiring(s.sessionID(item.ID), item.ExpiredAt)
+		notified++
+	}
+	return notified, nil
+}
+
+func (s *ManagerStore) notifyExpiring() {
+	wg := &s.root().wg
+	wg.Add(1)
+	defer wg.Done()
+
+	if _, err := s.NotifyExpiring(nil); err != nil {
+		s.errorf(err.Error())
+	}
+}
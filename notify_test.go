@@ -0,0 +1,60 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNotifyExpiring(t *testing.T) {
+	notified := make(map[string]time.Time)
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName:          "session_notify",
+		ExpiryNoticeWindow: time.Minute,
+		OnExpiring: func(sid string, expiredAt time.Time) {
+			notified[sid] = expiredAt
+		},
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test notification of sessions about to expire", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+		later := newSid()
+		defer mstore.Delete(ctx, later)
+
+		store, err := mstore.Create(ctx, sid, 30)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		store, err = mstore.Create(ctx, later, 3600)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		n, err := mstore.NotifyExpiring(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+		So(notified, ShouldContainKey, sid)
+		So(notified, ShouldNotContainKey, later)
+
+		n, err = mstore.NotifyExpiring(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 0)
+
+		_, err = mstore.Update(ctx, sid, 30)
+		So(err, ShouldBeNil)
+		n, err = mstore.NotifyExpiring(ctx)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,147 @@
+package gorm
+
+import (
+	"io"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Option Configures a store created by New
+type Option func(*options)
+
+type options struct {
+	cfg       Config
+	output    io.Writer
+	outputSet bool
+}
+
+// WithConfig Uses cfg as the base configuration, options after it override its fields
+func WithConfig(cfg Config) Option {
+	return func(o *options) {
+		o.cfg = cfg
+	}
+}
+
+// WithTableName Specify the stored table name (default session)
+func WithTableName(name string) Option {
+	return func(o *options) {
+		o.cfg.TableName = name
+	}
+}
+
+// WithGCInterval Time interval for executing GC (default 10 minutes)
+func WithGCInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cfg.GCIntervalDuration = interval
+	}
+}
+
+// WithGCJitter Varies every GC interval randomly by up to fraction of it, e.g. 0.2 for ±20%
+func WithGCJitter(fraction float64) Option {
+	return func(o *options) {
+		o.cfg.GCJitter = fraction
+	}
+}
+
+// WithOutput Where the store writes its errors and diagnostics (default os.Stderr, nil discards them)
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+		o.outputSet = true
+	}
+}
+
+// WithSIDPrefix Prefix of the sids stored by the store, see Config.SIDPrefix
+func WithSIDPrefix(prefix string) Option {
+	return func(o *options) {
+		o.cfg.SIDPrefix = prefix
+	}
+}
+
+// WithSigner Signs the stored values, see Config.Signer
+func WithSigner(signer Signer) Option {
+	return func(o *options) {
+		o.cfg.Signer = signer
+	}
+}
+
+// WithCompression Compresses the stored values with a registered Compressor, see Config.Compression
+func WithCompression(tag byte) Option {
+	return func(o *options) {
+		o.cfg.Compression = tag
+	}
+}
+
+// WithCompressionThreshold Stores values shorter than size bytes uncompressed, see Config.Compression
+func WithCompressionThreshold(size int) Option {
+	return func(o *options) {
+		o.cfg.CompressionThreshold = size
+	}
+}
+
+// WithCompressionDictionary Compresses the values with a trained dictionary, see Config.CompressionDictionary
+func WithCompressionDictionary() Option {
+	return func(o *options) {
+		o.cfg.CompressionDictionary = true
+	}
+}
+
+// WithEncryption Encrypts the stored values with AES-GCM, the previous keys
+// only decrypt values written before a key rotation, see Config.EncryptionKey
+func WithEncryption(key []byte, previous ...[]byte) Option {
+	return func(o *options) {
+		o.cfg.EncryptionKey = key
+		o.cfg.DecryptionKeys = previous
+	}
+}
+
+// WithLogger Logs the operations to a structured logger, e.g. a *slog.Logger, see Config.Logger
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.cfg.Logger = logger
+	}
+}
+
+// WithMetrics Reports the operations and GC runs to sink, see Config.Metrics
+func WithMetrics(sink MetricsSink) Option {
+	return func(o *options) {
+		o.cfg.Metrics = sink
+	}
+}
+
+// WithTracer Starts a span per operation and GC run, see Config.Tracer
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) {
+		o.cfg.Tracer = tracer
+	}
+}
+
+// WithMaxSessionAge Expires sessions created longer ago, see Config.MaxSessionAge
+func WithMaxSessionAge(age time.Duration) Option {
+	return func(o *options) {
+		o.cfg.MaxSessionAge = age
+	}
+}
+
+// WithNegativeCache Remembers sids that do not exist, see Config.NegativeCacheTTL
+func WithNegativeCache(ttl time.Duration, size int) Option {
+	return func(o *options) {
+		o.cfg.NegativeCacheTTL = ttl
+		o.cfg.NegativeCacheSize = size
+	}
+}
+
+// New Create an instance of a gorm store on an existing db configured by opts,
+// e.g. New(db, WithTableName("sess"), WithGCInterval(5*time.Minute))
+func New(db *gorm.DB, opts ...Option) (*ManagerStore, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if !o.outputSet {
+		return NewStoreWithConfig(db, o.cfg)
+	}
+	return newManagerStore(db, o.cfg, o.output)
+}
@@ -0,0 +1,52 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNew(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	mstore, err := New(db,
+		WithConfig(Config{TableName: "session", SIDPrefix: "ignored:"}),
+		WithTableName("session_options"),
+		WithGCInterval(5*time.Minute),
+		WithSIDPrefix("app:"),
+		WithOutput(buf),
+	)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test creating a store with functional options", t, func() {
+		So(mstore.tableName, ShouldEqual, "session_options")
+		So(mstore.idPrefix, ShouldEqual, "app:")
+		So(mstore.stdout, ShouldEqual, buf)
+
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		var count int
+		So(mstore.db.Where("id=?", "app:"+sid).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,64 @@
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// peerNotification The payload NOTIFYed on Config.NotifyChannel
+type peerNotification struct {
+	Op       string `json:"op"`
+	SID      string `json:"sid"`
+	Instance string `json:"instance"`
+}
+
+// peerOps The events of the operations NOTIFYed to the peers
+var peerOps = map[string]EventType{
+	"delete":  EventDeleted,
+	"refresh": EventRefreshed,
+}
+
+// notifyPeers NOTIFYs the other instances of an operation on sid on Config.NotifyChannel,
+// inside a transaction the notification is delivered on commit
+func (s *ManagerStore) notifyPeers(op, sid string) error {
+	if s.notifyChannel == "" {
+		return nil
+	}
+
+	buf, err := json.Marshal(peerNotification{Op: op, SID: sid, Instance: s.root().instanceID})
+	if err != nil {
+		return err
+	}
+	return s.db.Exec("SELECT pg_notify(?, ?)", s.notifyChannel, string(buf)).Error
+}
+
+// ListenPeers Publishes the deletions and refreshes that other instances NOTIFY on Config.NotifyChannel
+// to the subscribers of this store as events with Peer set, e.g. to drop in-memory copies of the sessions.
+// It reads the notification payloads until ctx is done or payloads is closed, e.g. the Extra fields of
+// the notifications of a *pq.Listener that LISTENs on the channel; its own notifications are skipped.
+func (s *ManagerStore) ListenPeers(ctx context.Context, payloads <-chan string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case payload, ok := <-payloads:
+			if !ok {
+				return nil
+			}
+
+			var n peerNotification
+			if err := json.Unmarshal([]byte(payload), &n); err != nil {
+				if logger := s.internalLogger(); logger != nil {
+					logger.WarnContext(context.Background(), "gorm session: malformed peer notification", "error", err)
+				}
+				continue
+			}
+			typ, ok := peerOps[n.Op]
+			if !ok || n.Instance == s.root().instanceID {
+				continue
+			}
+			s.publishEvent(SessionEvent{Type: typ, SID: n.SID, Time: time.Now(), Peer: true})
+		}
+	}
+}
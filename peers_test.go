@@ -0,0 +1,72 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListenPeers(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	_, err := NewManagerStore(Config{TableName: "session_peers", NotifyChannel: "sessions"}, "sqlite3", dsn)
+	if err == nil {
+		t.Error("NotifyChannel is accepted for sqlite3")
+		return
+	}
+
+	mstore, err := NewManagerStore(Config{TableName: "session_peers", NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+	mstore.instanceID = "self"
+
+	Convey("Test publishing the notifications of the peers", t, func() {
+		events := mstore.Subscribe()
+		defer mstore.Unsubscribe(events)
+
+		payloads := make(chan string, 4)
+		payloads <- `{"op":"delete","sid":"foo","instance":"other"}`
+		payloads <- `{"op":"delete","sid":"bar","instance":"self"}`
+		payloads <- `not json`
+		payloads <- `{"op":"refresh","sid":"baz","instance":"other"}`
+		close(payloads)
+		So(mstore.ListenPeers(context.Background(), payloads), ShouldBeNil)
+
+		So(events, ShouldHaveLength, 2)
+		event := <-events
+		So(event.Type, ShouldEqual, EventDeleted)
+		So(event.SID, ShouldEqual, "foo")
+		So(event.Peer, ShouldBeTrue)
+		event = <-events
+		So(event.Type, ShouldEqual, EventRefreshed)
+		So(event.SID, ShouldEqual, "baz")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		So(mstore.ListenPeers(ctx, make(chan string)), ShouldEqual, context.Canceled)
+	})
+
+	Convey("Test a refresh is published to the subscribers", t, func() {
+		ctx := context.Background()
+		events := mstore.Subscribe()
+		defer mstore.Unsubscribe(events)
+
+		oldsid, sid := newSid(), newSid()
+		defer mstore.Delete(ctx, sid)
+		store, err := mstore.Create(ctx, oldsid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		_, err = mstore.Refresh(ctx, oldsid, sid, 60)
+		So(err, ShouldBeNil)
+
+		event := <-events
+		So(event.Type, ShouldEqual, EventRefreshed)
+		So(event.SID, ShouldEqual, oldsid)
+		So(event.Peer, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,37 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// poolMessage The message of the connection pool statistics
+const poolMessage = "gorm session: pool"
+
+// PoolStats Returns the statistics of the connection pool of the store,
+// e.g. to tune MaxIdleConns and ConnMaxLifetime
+func (s *ManagerStore) PoolStats() sql.DBStats {
+	return s.root().db.DB().Stats()
+}
+
+// logPoolStats Logs the pool statistics at info level, the wait and closed counts
+// since the previous GC run
+func (s *ManagerStore) logPoolStats() {
+	root := s.root()
+	stats := s.PoolStats()
+	prev := root.poolStats
+	root.poolStats = stats
+	s.observePool(stats)
+
+	logger := s.internalLogger()
+	if logger == nil {
+		return
+	}
+	logger.InfoContext(context.Background(), poolMessage, "table", s.tableName,
+		"open", stats.OpenConnections, "in_use", stats.InUse, "idle", stats.Idle,
+		"wait_count", stats.WaitCount-prev.WaitCount,
+		"wait_duration", stats.WaitDuration-prev.WaitDuration,
+		"max_idle_closed", stats.MaxIdleClosed-prev.MaxIdleClosed,
+		"max_idle_time_closed", stats.MaxIdleTimeClosed-prev.MaxIdleTimeClosed,
+		"max_lifetime_closed", stats.MaxLifetimeClosed-prev.MaxLifetimeClosed)
+}
@@ -0,0 +1,39 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPoolStats(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_pool", MaxIdleConns: 2, LogPoolStats: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test logging the connection pool statistics", t, func() {
+		_, err := mstore.Check(context.Background(), newSid())
+		So(err, ShouldBeNil)
+		So(mstore.PoolStats().OpenConnections, ShouldBeGreaterThan, 0)
+
+		buf := new(bytes.Buffer)
+		mstore.output = newOutputLogger(buf)
+		mstore.logPoolStats()
+		So(buf.String(), ShouldStartWith, "[GORM-SESSION-POOL]: open=")
+		So(buf.String(), ShouldContainSubstring, "max_lifetime_closed=0\n")
+
+		// a configured Logger receives them as a structured record instead
+		buf.Reset()
+		mstore.logger = NewWriterLogger(buf, false)
+		defer func() { mstore.logger = nil }()
+		mstore.logPoolStats()
+		So(buf.String(), ShouldContainSubstring, " INFO gorm session: pool table=session_pool open=")
+	})
+}
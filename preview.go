@@ -0,0 +1,87 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// GCPreview The sessions a GC run would delete now, counted by the reason
+type GCPreview struct {
+	Expired  int64    // Sessions past their expiry
+	Aged     int64    // Unexpired sessions older than MaxSessionAge
+	Overflow int64    // Remaining sessions beyond MaxTableRows, closest to expiry
+	Sample   []string // Up to limit sids of the sessions, in the order above
+}
+
+// PreviewExpired Reports which sessions of the store's sid prefix a GC run would delete now
+// without deleting anything, e.g. before enabling MaxSessionAge or MaxTableRows on a shared table,
+// with up to limit sample sids
+func (s *ManagerStore) PreviewExpired(ctx context.Context, limit int) (GCPreview, error) {
+	if s.isClosed() {
+		return GCPreview{}, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return GCPreview{}, err
+	}
+
+	var preview GCPreview
+	now := time.Now()
+	expired := s.scoped().Where("expired_at<=?", now)
+	if err := expired.Count(&preview.Expired).Error; err != nil {
+		return preview, err
+	}
+	if err := preview.sample(s, expired.Order("id"), preview.Expired, limit); err != nil {
+		return preview, err
+	}
+
+	live := s.scoped().Where("expired_at>?", now)
+	if s.maxAge > 0 {
+		cutoff := now.Add(-s.maxAge)
+		aged := live.Where("created_at<=?", cutoff)
+		if err := aged.Count(&preview.Aged).Error; err != nil {
+			return preview, err
+		}
+		if err := preview.sample(s, aged.Order("id"), preview.Aged, limit); err != nil {
+			return preview, err
+		}
+		live = live.Where("created_at>?", cutoff)
+	}
+
+	if s.maxTableRows > 0 {
+		var count int64
+		if err := live.Count(&count).Error; err != nil {
+			return preview, err
+		} else if count > int64(s.maxTableRows) {
+			preview.Overflow = count - int64(s.maxTableRows)
+			if err := preview.sample(s, live.Order("expired_at"), preview.Overflow, limit); err != nil {
+				return preview, err
+			}
+		}
+	}
+	return preview, nil
+}
+
+// sample Appends the sids of up to n rows of query to the sample, which holds at most limit sids
+func (p *GCPreview) sample(s *ManagerStore, query *gorm.DB, n int64, limit int) error {
+	remaining := int64(limit - len(p.Sample))
+	if n < remaining {
+		remaining = n
+	}
+	if remaining <= 0 {
+		return nil
+	}
+
+	var keys []string
+	if err := query.Limit(remaining).Pluck("id", &keys).Error; err != nil {
+		return err
+	}
+	for _, key := range keys {
+		p.Sample = append(p.Sample, s.sessionID(key))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreviewExpired(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName:     "session_preview",
+		NoBackground:  true,
+		MaxSessionAge: time.Hour,
+		MaxTableRows:  2,
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test previewing the sessions GC would delete", t, func() {
+		ctx := context.Background()
+		mstore.db.Delete(nil)
+
+		save := func(expired int64, createdAt time.Time) string {
+			sid := newSid()
+			store, err := mstore.Create(ctx, sid, expired)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+			So(mstore.db.Where("id=?", sid).Update("created_at", createdAt).Error, ShouldBeNil)
+			return sid
+		}
+		expired := save(-60, time.Now())
+		aged := save(60, time.Now().Add(-2*time.Hour))
+		soon := save(60, time.Now())
+		save(120, time.Now())
+		save(180, time.Now())
+
+		preview, err := mstore.PreviewExpired(ctx, 10)
+		So(err, ShouldBeNil)
+		So(preview.Expired, ShouldEqual, 1)
+		So(preview.Aged, ShouldEqual, 1)
+		So(preview.Overflow, ShouldEqual, 1)
+		So(preview.Sample, ShouldResemble, []string{expired, aged, soon})
+
+		preview, err = mstore.PreviewExpired(ctx, 2)
+		So(err, ShouldBeNil)
+		So(preview.Sample, ShouldResemble, []string{expired, aged})
+
+		// nothing was deleted
+		var count int
+		So(mstore.db.Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 5)
+
+		So(mstore.GC(ctx), ShouldBeNil)
+		preview, err = mstore.PreviewExpired(ctx, 10)
+		So(err, ShouldBeNil)
+		So(preview.Expired+preview.Aged+preview.Overflow, ShouldEqual, 0)
+		So(preview.Sample, ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,48 @@
+package gorm
+
+import (
+	"context"
+
+	"github.com/go-session/session"
+)
+
+// RefreshWith Refreshes oldsid to sid like Refresh and lets mutate rewrite the values of
+// the new session before they are saved, in one transaction, e.g. to update the role claims
+// while rotating the sid at a privilege escalation. If Refresh or Save fails the old session
+// is kept unchanged and sid is not created; the values are not saved again if mutate is nil.
+func (s *ManagerStore) RefreshWith(ctx context.Context, oldsid, sid string, expired int64, mutate func(values map[string]interface{})) (session.Store, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var store *Store
+	err = s.WithTransaction(ctx, func(tx *ManagerStore) error {
+		refreshed, err := tx.Refresh(ctx, oldsid, sid, expired)
+		if err != nil {
+			return err
+		}
+
+		store = refreshed.(*Store)
+		if mutate == nil {
+			return nil
+		}
+
+		store.Lock()
+		mutate(store.values)
+		store.Unlock()
+		return store.Save()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the transaction is over, later saves run on the store itself
+	store.mstore = s.unbind()
+	return store, nil
+}
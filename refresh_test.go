@@ -0,0 +1,77 @@
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRefreshWith(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_refresh_with"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test rotating the sid and rewriting the values at once", t, func() {
+		ctx := context.Background()
+		oldsid, sid := newSid(), newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, oldsid, expired)
+		So(err, ShouldBeNil)
+		store.Set("role", "guest")
+		So(store.Save(), ShouldBeNil)
+
+		refreshed, err := mstore.RefreshWith(ctx, oldsid, sid, expired, func(values map[string]interface{}) {
+			values["role"] = "admin"
+		})
+		So(err, ShouldBeNil)
+		So(refreshed.SessionID(), ShouldEqual, sid)
+
+		ok, err := mstore.Check(ctx, oldsid)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		store, err = mstore.Update(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		role, _ := store.Get("role")
+		So(role, ShouldEqual, "admin")
+
+		refreshed.Set("name", "foo")
+		So(refreshed.Save(), ShouldBeNil)
+	})
+
+	Convey("Test a failed save keeps the old session", t, func() {
+		ctx := context.Background()
+		oldsid, sid := newSid(), newSid()
+		defer mstore.Delete(ctx, oldsid)
+
+		store, err := mstore.Create(ctx, oldsid, expired)
+		So(err, ShouldBeNil)
+		store.Set("role", "guest")
+		So(store.Save(), ShouldBeNil)
+
+		_, err = mstore.RefreshWith(ctx, oldsid, sid, expired, func(values map[string]interface{}) {
+			values["role"] = "admin"
+			jsonMarshal = func(interface{}) ([]byte, error) { return nil, errors.New("marshal failed") }
+		})
+		jsonMarshal = json.Marshal
+		So(err, ShouldNotBeNil)
+
+		ok, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		store, err = mstore.Update(ctx, oldsid, expired)
+		So(err, ShouldBeNil)
+		role, _ := store.Get("role")
+		So(role, ShouldEqual, "guest")
+	})
+}
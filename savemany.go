@@ -0,0 +1,130 @@
+package gorm
+
+import (
+	"context"
+)
+
+// defaultBatchSize The number of sessions SaveMany writes per statement by default
+const defaultBatchSize = 100
+
+// SaveMany Saves many sessions of the store in batches of batchSize (default 100),
+// e.g. for a background job migrating a value key, every batch is written
+// with one multi-row upsert inside its own transaction, so the batches before
+// a failing one stay saved, a batch with a revoked session fails with ErrSessionRevoked
+func (s *ManagerStore) SaveMany(ctx context.Context, stores []*Store, batchSize int) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	for start := 0; start < len(stores); start += batchSize {
+		end := start + batchSize
+		if end > len(stores) {
+			end = len(stores)
+		}
+
+		err := s.saveBatch(ctx, stores[start:end])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveBatch Writes the rows of the sessions in one transaction
+func (s *ManagerStore) saveBatch(ctx context.Context, stores []*Store) error {
+	items := make([]*SessionItem, len(stores))
+	fields := make([]map[string]interface{}, len(stores))
+	for i, store := range stores {
+		item, f, err := store.row(SaveOptions{})
+		if err != nil {
+			return err
+		}
+		items[i], fields[i] = item, f
+	}
+
+	db := s.db.BeginTx(ctx, nil)
+	if err := db.Error; err != nil {
+		return err
+	}
+	defer db.RollbackUnlessCommitted()
+
+	tx := s.withDB(db)
+	// the sessions deleted as empty are reported once the batch commits
+	tx.txHooks = new([]func())
+	commit := func() error {
+		if err := db.Commit().Error; err != nil {
+			return err
+		}
+		for _, hook := range *tx.txHooks {
+			hook()
+		}
+		return nil
+	}
+
+	if s.emptyPolicy != EmptyKeep {
+		var saving []*Store
+		var savingItems []*SessionItem
+		var savingFields []map[string]interface{}
+		for i, store := range stores {
+			ok, err := store.saveEmpty(tx)
+			if err != nil {
+				return err
+			} else if !ok {
+				saving = append(saving, store)
+				savingItems = append(savingItems, items[i])
+				savingFields = append(savingFields, fields[i])
+			}
+		}
+		stores, items, fields = saving, savingItems, savingFields
+		if len(items) == 0 {
+			return commit()
+		}
+	}
+
+	ok, err := tx.upsertMany(items, fields)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		if !ok {
+			err = tx.insertOrUpdate(item, fields[i])
+		} else if tx.separateValues {
+			err = tx.writeValue(item.ID, item.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.ID
+	}
+	if ok, err := tx.revoked(keys...); err != nil {
+		return err
+	} else if ok {
+		return ErrSessionRevoked
+	}
+
+	if err := commit(); err != nil {
+		return err
+	}
+	for i, item := range items {
+		s.missing.remove(item.ID)
+		stores[i].saved(item, fields[i])
+		if s.usersKey != "" {
+			uid, _ := stores[i].Get(s.usersKey)
+			s.observeUser(uid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSaveMany(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_save_many", Signer: SignerFunc(hmacSign)}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	separate, err := NewManagerStore(Config{TableName: "session_save_many_separate", SeparateValues: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer separate.Close()
+
+	Convey("Test saving many sessions in batches", t, func() {
+		ctx := context.Background()
+		for _, writer := range []*ManagerStore{mstore, separate} {
+			stores := make([]*Store, 250)
+			for i := range stores {
+				store, err := writer.Create(ctx, newSid(), 60)
+				So(err, ShouldBeNil)
+				store.Set("version", 1)
+				stores[i] = store.(*Store)
+			}
+			So(writer.SaveMany(ctx, stores, 100), ShouldBeNil)
+
+			for _, store := range stores {
+				store.Set("version", 2)
+			}
+			So(writer.SaveMany(ctx, stores, 0), ShouldBeNil)
+
+			for _, s := range []*Store{stores[0], stores[249]} {
+				store, err := writer.Update(ctx, s.SessionID(), 60)
+				So(err, ShouldBeNil)
+				version, ok := store.Get("version")
+				So(ok, ShouldBeTrue)
+				So(version, ShouldEqual, 2)
+			}
+		}
+	})
+}
+
+func TestSaveManyEmptyHooks(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	var deleted []string
+	mstore, err := NewManagerStore(Config{
+		TableName:    "session_save_many_empty",
+		EmptyPolicy:  EmptyDelete,
+		TombstoneTTL: time.Minute,
+		OnDelete:     func(sid string) { deleted = append(deleted, sid) },
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test the emptied sessions of a batch are reported once it commits", t, func() {
+		ctx := context.Background()
+		stores := make([]*Store, 2)
+		for i := range stores {
+			store, err := mstore.Create(ctx, newSid(), 60)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+			stores[i] = store.(*Store)
+		}
+		emptied, revoked := stores[0], stores[1]
+		emptied.Delete("foo")
+		So(mstore.Delete(ctx, revoked.SessionID()), ShouldBeNil)
+		deleted = nil
+
+		So(mstore.SaveMany(ctx, stores, 0), ShouldEqual, ErrSessionRevoked)
+		So(deleted, ShouldBeEmpty)
+		exists, err := mstore.Check(ctx, emptied.SessionID())
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		So(mstore.SaveMany(ctx, stores[:1], 0), ShouldBeNil)
+		So(deleted, ShouldResemble, []string{emptied.SessionID()})
+	})
+}
@@ -0,0 +1,458 @@
+package gorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultValueColumnSize = 2048
+	defaultWarningRatio    = 0.75
+)
+
+// valueTypes The base types allowed for ValueColumnType, the type name is part of the DDL
+var valueTypes = map[string]bool{
+	"text": true, "tinytext": true, "mediumtext": true, "longtext": true, "ntext": true, "clob": true,
+	"varchar": true, "nvarchar": true, "char": true, "nchar": true,
+	"blob": true, "mediumblob": true, "longblob": true, "bytea": true, "varbinary": true,
+	"json": true, "jsonb": true,
+}
+
+// valueTypePattern Matches a type name with an optional length, e.g. VARCHAR(4096) or NVARCHAR(MAX)
+var valueTypePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*(\((\d+|[Mm][Aa][Xx])\))?$`)
+
+// jsonType Reports whether the value column type only accepts JSON documents
+func jsonType(valueType string) bool {
+	typ := baseType(valueType)
+	return typ == "json" || typ == "jsonb"
+}
+
+// emptyJSON The value written for a session without values to a JSON value column
+const emptyJSON = "{}"
+
+// emptyValue Returns the value of a session without values, JSON types reject an empty string
+func (s *ManagerStore) emptyValue() string {
+	if jsonType(s.valueType) {
+		return emptyJSON
+	}
+	return ""
+}
+
+// checkValueType Returns an error if ValueColumnType is not a known type,
+// or a JSON type while the values are compressed or encrypted, which are not JSON
+func checkValueType(cfg Config) error {
+	if cfg.ValueColumnType == "" {
+		return nil
+	} else if !valueTypePattern.MatchString(cfg.ValueColumnType) || !valueTypes[baseType(cfg.ValueColumnType)] {
+		return fmt.Errorf("gorm session: unsupported ValueColumnType %q", cfg.ValueColumnType)
+	}
+
+	if jsonType(cfg.ValueColumnType) &&
+		(cfg.Compression != 0 || cfg.EncryptionKey != nil || cfg.CompressionDictionary) {
+		return fmt.Errorf("gorm session: ValueColumnType %s cannot hold compressed or encrypted values", cfg.ValueColumnType)
+	}
+	return nil
+}
+
+// sessionModel Returns the model used to create the session table,
+// a SessionItem whose value column has the given size or type
+func sessionModel(valueSize int, valueType string) interface{} {
+	return resizeValue(&SessionItem{}, valueSize, valueType)
+}
+
+// resizeValue Returns a copy of the model whose value column has the given size,
+// or the given type, which takes precedence
+func resizeValue(model interface{}, valueSize int, valueType string) interface{} {
+	tag := fmt.Sprintf(`gorm:"column:value;size:%d;"`, valueSize)
+	if valueType != "" {
+		tag = fmt.Sprintf(`gorm:"column:value;type:%s;"`, valueType)
+	} else if valueSize <= 0 || valueSize == defaultValueColumnSize {
+		return model
+	}
+
+	typ := reflect.TypeOf(model).Elem()
+	fields := make([]reflect.StructField, typ.NumField())
+	for i := range fields {
+		fields[i] = typ.Field(i)
+		if fields[i].Name == "Value" {
+			fields[i].Tag = reflect.StructTag(tag)
+		}
+	}
+	return reflect.New(reflect.StructOf(fields)).Interface()
+}
+
+// optionalColumns Returns the models of the columns that the features
+// enabled in cfg add to the session table
+func optionalColumns(cfg Config) []interface{} {
+	var columns []interface{}
+	if cfg.Signer != nil {
+		columns = append(columns, &signatureColumn{})
+	}
+	if cfg.OnExpiring != nil {
+		columns = append(columns, &notifiedAtColumn{})
+	}
+	if cfg.EnableLeases {
+		columns = append(columns, &leaseColumns{})
+	}
+	if cfg.EnableIdempotencyKeys {
+		columns = append(columns, &idempotencyColumn{})
+	}
+	if cfg.TrackAuthentication {
+		columns = append(columns, &authenticatedColumn{})
+	}
+	if len(cfg.ExtractColumns) > 0 {
+		columns = append(columns, extractModel(cfg.ExtractColumns))
+	}
+	return columns
+}
+
+// autoMigrate Runs AutoMigrate of model on db, which fails if another instance
+// migrates concurrently, the error is only returned if the schema is still incomplete
+func (s *ManagerStore) autoMigrate(db *gorm.DB, model interface{}) error {
+	err := db.AutoMigrate(model).Error
+	if err != nil && s.missingColumn(db, model) != "" {
+		return err
+	}
+	return nil
+}
+
+// missingColumn Returns the first column of model that the table of db lacks,
+// the table name itself if it does not exist, empty if none is missing
+func (s *ManagerStore) missingColumn(db *gorm.DB, model interface{}) string {
+	scope := db.NewScope(model)
+	table := scope.TableName()
+	dialect := db.Dialect()
+	if !dialect.HasTable(table) {
+		return table
+	}
+
+	for _, field := range scope.GetModelStruct().StructFields {
+		if field.IsNormal && !field.IsIgnored && !dialect.HasColumn(table, field.DBName) {
+			return field.DBName
+		}
+	}
+	return ""
+}
+
+// checkValueSize Calls the OnLargeValue hook if value approaches the size of the value column,
+// a column of a configured type has no size unless it is configured too
+func (s *ManagerStore) checkValueSize(sid, value string) {
+	if s.onLargeValue == nil || (s.valueType != "" && s.valueSize <= 0) {
+		return
+	}
+
+	limit := s.valueSize
+	if limit <= 0 {
+		limit = defaultValueColumnSize
+	}
+	ratio := s.warningRatio
+	if ratio <= 0 {
+		ratio = defaultWarningRatio
+	}
+
+	if float64(len(value)) >= float64(limit)*ratio {
+		s.onLargeValue(sid, len(value), limit)
+	}
+}
+
+// GenerateDDL Returns the CREATE TABLE/INDEX statements a store with cfg
+// runs against an empty database of the dialect (mysql, postgres, sqlite3, mssql),
+// so the schema can be reviewed and applied by a migration pipeline
+func GenerateDDL(dialect string, cfg Config) (string, error) {
+	if _, ok := gorm.GetDialect(dialect); !ok {
+		return "", fmt.Errorf("gorm session: unknown dialect %s", dialect)
+	}
+
+	if err := checkExtractRules(cfg.ExtractColumns); err != nil {
+		return "", err
+	} else if err := checkValueType(cfg); err != nil {
+		return "", err
+	}
+	templates, err := parseTableTemplates(cfg.TableTemplates)
+	if err != nil {
+		return "", err
+	}
+
+	recorder := new(ddlRecorder)
+	db, err := gorm.Open(dialect, recorder)
+	if err != nil {
+		return "", err
+	}
+
+	tableName := cfg.TableName
+	if tableName == "" {
+		tableName = "session"
+	}
+	db = db.Table(tableName)
+
+	model := sessionModel(cfg.ValueColumnSize, cfg.ValueColumnType)
+	if cfg.SeparateValues {
+		model = &sessionMeta{}
+	}
+	if tpl, ok := templates[dialect]; ok {
+		ddl, err := renderTableTemplate(db, tpl, model, cfg.ValueColumnSize)
+		if err != nil {
+			return "", err
+		}
+		err = db.Exec(ddl).Error
+	} else {
+		err = db.CreateTable(model).Error
+	}
+	if err != nil {
+		return "", err
+	}
+
+	scope := db.NewScope(nil)
+	if !cfg.SkipIndexCreation {
+		recorder.statements = append(recorder.statements, fmt.Sprintf("CREATE INDEX %s ON %v(%v)",
+			expiredIndex(tableName), scope.QuotedTableName(), scope.Quote("expired_at")))
+	}
+
+	for _, columns := range optionalColumns(cfg) {
+		for _, field := range db.NewScope(columns).GetModelStruct().StructFields {
+			recorder.statements = append(recorder.statements, fmt.Sprintf("ALTER TABLE %v ADD %v %v",
+				scope.QuotedTableName(), scope.Quote(field.DBName), db.Dialect().DataTypeOf(field)))
+		}
+	}
+	for _, rule := range cfg.ExtractColumns {
+		recorder.statements = append(recorder.statements, fmt.Sprintf("CREATE INDEX %s ON %v(%v)",
+			extractIndex(tableName, rule.Column), scope.QuotedTableName(), scope.Quote(rule.Column)))
+	}
+
+	if cfg.SeparateValues {
+		err = db.Table(valueTable(tableName)).CreateTable(valueModel(cfg.ValueColumnSize, cfg.ValueColumnType)).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.EnableChallenges {
+		table := db.Table(challengeTable(tableName))
+		err = table.CreateTable(&challengeItem{}).Error
+		if err != nil {
+			return "", err
+		}
+
+		scope := table.NewScope(nil)
+		recorder.statements = append(recorder.statements, fmt.Sprintf("CREATE INDEX %s ON %v(%v)",
+			challengeIndex(tableName), scope.QuotedTableName(), scope.Quote("session_id")))
+	}
+
+	if cfg.EnableStats {
+		err = db.Table(statsTable(tableName)).CreateTable(&statsItem{}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.UniqueUsersKey != "" {
+		err = db.Table(usersTable(tableName)).CreateTable(&usersItem{}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.QuarantineUndecodable {
+		model := resizeValue(&quarantineItem{}, cfg.ValueColumnSize, cfg.ValueColumnType)
+		err = db.Table(quarantineTable(tableName)).CreateTable(model).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.TombstoneTTL > 0 {
+		err = db.Table(tombstoneTable(tableName)).CreateTable(&tombstoneItem{}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.CompressionDictionary {
+		err = db.Table(dictionaryTable(tableName)).CreateTable(&dictionaryItem{}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.EnableAttempts {
+		err = db.Table(attemptTable(tableName)).CreateTable(&attemptItem{}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.VersionHandshake {
+		err = db.Table(metaTable(tableName)).CreateTable(&metaItem{}).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.EnableLineage {
+		table := db.Table(lineageTable(tableName))
+		err = table.CreateTable(&lineageItem{}).Error
+		if err != nil {
+			return "", err
+		}
+
+		scope := table.NewScope(nil)
+		recorder.statements = append(recorder.statements, fmt.Sprintf("CREATE INDEX idx_%s_new_id ON %v(%v)",
+			lineageTable(tableName), scope.QuotedTableName(), scope.Quote("new_id")))
+	}
+
+	return strings.Join(recorder.statements, ";\n") + ";\n", nil
+}
+
+var errDDLOnly = errors.New("gorm session: only DDL statements can be generated")
+
+// ddlRecorder A gorm.SQLCommon that records the statements instead of running them
+type ddlRecorder struct {
+	statements []string
+}
+
+func (r *ddlRecorder) Exec(query string, _ ...interface{}) (sql.Result, error) {
+	r.statements = append(r.statements, strings.TrimSpace(query))
+	return driver.RowsAffected(0), nil
+}
+
+func (r *ddlRecorder) Prepare(string) (*sql.Stmt, error) {
+	return nil, errDDLOnly
+}
+
+func (r *ddlRecorder) Query(string, ...interface{}) (*sql.Rows, error) {
+	return nil, errDDLOnly
+}
+
+func (r *ddlRecorder) QueryRow(string, ...interface{}) *sql.Row {
+	return nil
+}
+
+// valueColumnInfo Scans the attribute of the value column of table
+// from the information schema, false if the dialect has none
+func (s *ManagerStore) valueColumnInfo(table, attribute string, dest interface{}) (bool, error) {
+	var query string
+	dialect := s.db.Dialect()
+	switch dialect.GetName() {
+	case "mysql":
+		query = "SELECT %s FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA=? AND TABLE_NAME=? AND COLUMN_NAME=?"
+	case "postgres":
+		query = "SELECT %s FROM information_schema.columns WHERE table_catalog=? AND table_name=? AND column_name=?"
+	case "mssql":
+		query = "SELECT %s FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_CATALOG=? AND TABLE_NAME=? AND COLUMN_NAME=?"
+	default:
+		return false, nil
+	}
+
+	err := s.db.Raw(fmt.Sprintf(query, attribute), dialect.CurrentDatabase(), table, "value").Row().Scan(dest)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// valueColumnSize Returns the declared size of the value column of table,
+// 0 if the dialect does not enforce it or the column is unbounded
+func (s *ManagerStore) valueColumnSize(table string) (int, error) {
+	var size sql.NullInt64
+	ok, err := s.valueColumnInfo(table, "CHARACTER_MAXIMUM_LENGTH", &size)
+	if err != nil || !ok || !size.Valid || size.Int64 < 0 {
+		return 0, err
+	}
+	return int(size.Int64), nil
+}
+
+// valueColumnType Returns the data type of the value column of table in lower case
+// without its size, e.g. varchar or mediumtext, empty if the dialect has no information schema
+func (s *ManagerStore) valueColumnType(table string) (string, error) {
+	var typ sql.NullString
+	ok, err := s.valueColumnInfo(table, "DATA_TYPE", &typ)
+	if err != nil || !ok {
+		return "", err
+	}
+	return baseType(typ.String), nil
+}
+
+// baseType Returns the name of a column type without its size in lower case,
+// the long names postgres reports are shortened to the names used in DDL
+func baseType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = strings.TrimSpace(typ[:i])
+	}
+
+	switch typ {
+	case "character varying":
+		return "varchar"
+	case "character":
+		return "char"
+	}
+	return typ
+}
+
+// checkValueColumn Compares the existing value column with the configured type, or the
+// configured size without a type, and alters the column if it differs, or is smaller, and migrate is set
+func (s *ManagerStore) checkValueColumn(table string, size int, migrate bool) error {
+	if s.valueType != "" {
+		current, err := s.valueColumnType(table)
+		if err != nil || current == "" || current == baseType(s.valueType) {
+			return err
+		} else if !migrate {
+			return fmt.Errorf("gorm session: value column of table %s is %s but ValueColumnType is %s, enable MigrateValueColumn to alter it", table, current, s.valueType)
+		}
+		return s.modifyValueColumn(table, size)
+	}
+
+	current, err := s.valueColumnSize(table)
+	if err != nil {
+		return err
+	} else if current == 0 || current >= size {
+		return nil
+	} else if !migrate {
+		return fmt.Errorf("gorm session: value column of table %s holds %d characters but ValueColumnSize is %d, enable MigrateValueColumn to alter it", table, current, size)
+	}
+	return s.modifyValueColumn(table, size)
+}
+
+// modifyValueColumn Alters the value column of table to the configured type or size
+func (s *ManagerStore) modifyValueColumn(table string, size int) error {
+	if jsonType(s.valueType) {
+		if err := s.prepareJSONValues(table); err != nil {
+			return err
+		}
+	}
+
+	field, _ := s.db.NewScope(sessionModel(size, s.valueType)).FieldByName("Value")
+	typ := s.db.Dialect().DataTypeOf(field.StructField)
+	if s.db.Dialect().GetName() != "postgres" {
+		return s.db.Table(table).ModifyColumn("value", typ).Error
+	}
+
+	// postgres does not cast text to every type implicitly, e.g. jsonb or bytea
+	scope := s.db.Table(table).NewScope(nil)
+	column := scope.Quote("value")
+	return s.db.Exec(fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v TYPE %v USING %v::%v",
+		scope.QuotedTableName(), column, typ, column, typ)).Error
+}
+
+// prepareJSONValues Replaces the empty values of table by an empty JSON object before the
+// column becomes a JSON type, and returns an error if a value is compressed or encrypted
+func (s *ManagerStore) prepareJSONValues(table string) error {
+	db := s.db.Table(table)
+	var count int
+	err := db.Where("value LIKE ? OR value LIKE ? OR value LIKE ?",
+		string(compressedMarker)+"%", string(encryptedMarker)+"%", string(dictionaryMarker)+"%").Count(&count).Error
+	if err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("gorm session: %d values of table %s are compressed or encrypted, "+
+			"rewrite them with MigrateValues before altering the column to %s", count, table, s.valueType)
+	}
+	return db.Where("value=?", "").Update("value", emptyJSON).Error
+}
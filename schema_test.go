@@ -0,0 +1,165 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValueColumnSize(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	db.DropTableIfExists("session_value_size")
+
+	Convey("Test creating the table with a custom value column size", t, func() {
+		mstore, err := NewStoreWithConfig(db, Config{TableName: "session_value_size", ValueColumnSize: 4096})
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		var ddl string
+		err = db.Raw("SELECT sql FROM sqlite_master WHERE type='table' AND name=?", "session_value_size").Row().Scan(&ddl)
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, `"value" varchar(4096)`)
+
+		size, err := mstore.valueColumnSize("session_value_size")
+		So(err, ShouldBeNil)
+		So(size, ShouldEqual, 0)
+	})
+}
+
+func TestValueColumnType(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	db.DropTableIfExists("session_value_type")
+
+	Convey("Test creating the table with a custom value column type", t, func() {
+		mstore, err := NewStoreWithConfig(db, Config{TableName: "session_value_type", ValueColumnType: "TEXT"})
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		var ddl string
+		err = db.Raw("SELECT sql FROM sqlite_master WHERE type='table' AND name=?", "session_value_type").Row().Scan(&ddl)
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, `"value" TEXT`)
+
+		So(baseType("VARCHAR(4096)"), ShouldEqual, "varchar")
+		So(baseType("character varying"), ShouldEqual, "varchar")
+		So(baseType("MEDIUMTEXT"), ShouldEqual, "mediumtext")
+	})
+
+	Convey("Test validating the value column type", t, func() {
+		So(checkValueType(Config{ValueColumnType: "VARCHAR(4096)"}), ShouldBeNil)
+		So(checkValueType(Config{ValueColumnType: "nvarchar(max)"}), ShouldBeNil)
+		So(checkValueType(Config{ValueColumnType: "JSONB"}), ShouldBeNil)
+		So(checkValueType(Config{ValueColumnType: "TEXT); DROP TABLE session; --"}), ShouldNotBeNil)
+		So(checkValueType(Config{ValueColumnType: "INTEGER"}), ShouldNotBeNil)
+		So(checkValueType(Config{ValueColumnType: "JSONB", Compression: GzipCompression}), ShouldNotBeNil)
+		So(checkValueType(Config{ValueColumnType: "JSON", CompressionDictionary: true}), ShouldNotBeNil)
+	})
+
+	Convey("Test storing empty sessions in a JSON value column", t, func() {
+		mstore, err := NewManagerStore(Config{TableName: "session_value_json", ValueColumnType: "JSONB"}, "sqlite3", os.TempDir()+"/gorm.db")
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+
+		ctx := context.Background()
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+		item, err := mstore.getItem(sid)
+		So(err, ShouldBeNil)
+		So(item.Value, ShouldEqual, "{}")
+
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		_, ok := store.Get("foo")
+		So(ok, ShouldBeFalse)
+
+		// values stored before the column became JSON
+		So(mstore.db.Where("id=?", sid).Update("value", "").Error, ShouldBeNil)
+		So(mstore.prepareJSONValues("session_value_json"), ShouldBeNil)
+		item, err = mstore.getItem(sid)
+		So(err, ShouldBeNil)
+		So(item.Value, ShouldEqual, "{}")
+
+		So(mstore.db.Where("id=?", sid).Update("value", "~01abc").Error, ShouldBeNil)
+		So(mstore.prepareJSONValues("session_value_json"), ShouldNotBeNil)
+	})
+}
+
+func TestGenerateDDL(t *testing.T) {
+	Convey("Test generating the schema of a store", t, func() {
+		ddl, err := GenerateDDL("mysql", Config{TableName: "sess", ValueColumnSize: 4096, Signer: SignerFunc(hmacSign)})
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, "CREATE TABLE `sess` (`id` varchar(255),`value` varchar(4096)")
+		So(ddl, ShouldContainSubstring, "CREATE INDEX idx_sess_expired_at ON `sess`(`expired_at`);")
+		So(ddl, ShouldContainSubstring, "ALTER TABLE `sess` ADD `signature` varchar(1024);")
+
+		ddl, err = GenerateDDL("mysql", Config{TableName: "sess", SkipIndexCreation: true})
+		So(err, ShouldBeNil)
+		So(ddl, ShouldNotContainSubstring, "CREATE INDEX")
+
+		ddl, err = GenerateDDL("postgres", Config{EnableChallenges: true})
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, `CREATE TABLE "session"`)
+		So(ddl, ShouldContainSubstring, `CREATE TABLE "session_challenges"`)
+
+		ddl, err = GenerateDDL("mysql", Config{SeparateValues: true})
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, "CREATE TABLE `session` (`id` varchar(255),`created_at`")
+		So(ddl, ShouldContainSubstring, "CREATE TABLE `session_values` (`id` varchar(255),`value` varchar(2048)")
+
+		ddl, err = GenerateDDL("postgres", Config{ValueColumnType: "JSONB"})
+		So(err, ShouldBeNil)
+		So(ddl, ShouldContainSubstring, `"value" JSONB`)
+
+		_, err = GenerateDDL("oracle", Config{})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestOnLargeValue(t *testing.T) {
+	var warnings []int
+	mstore, err := NewManagerStore(Config{
+		TableName:       "session_large_value",
+		ValueColumnSize: 100,
+		OnLargeValue: func(sid string, size, limit int) {
+			So(limit, ShouldEqual, 100)
+			warnings = append(warnings, size)
+		},
+	}, "sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test warning about values approaching the column size", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(warnings, ShouldBeEmpty)
+
+		store.Set("foo", strings.Repeat("x", 70))
+		So(store.Save(), ShouldBeNil)
+		So(warnings, ShouldHaveLength, 1)
+		So(warnings[0], ShouldBeGreaterThanOrEqualTo, 75)
+	})
+}
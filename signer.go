@@ -0,0 +1,22 @@
+package gorm
+
+// Signer Computes a detached signature of a session value,
+// the result is stored in the signature column so that other services
+// reading the table can verify the integrity of the payload,
+// sid is the stored id including any SIDPrefix
+type Signer interface {
+	Sign(sid string, value []byte) (string, error)
+}
+
+// SignerFunc The Signer implemented by a function
+type SignerFunc func(sid string, value []byte) (string, error)
+
+// Sign Calls f(sid, value)
+func (f SignerFunc) Sign(sid string, value []byte) (string, error) {
+	return f(sid, value)
+}
+
+// signatureColumn Used to migrate the signature column onto the session table
+type signatureColumn struct {
+	Signature string `gorm:"column:signature;size:1024;"`
+}
@@ -0,0 +1,58 @@
+package gorm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func hmacSign(sid string, value []byte) (string, error) {
+	h := hmac.New(sha256.New, []byte("secret"))
+	h.Write([]byte(sid))
+	h.Write(value)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func TestSignedValues(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_signed", Signer: SignerFunc(hmacSign)}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test detached signature of session values", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+
+		store, err := mstore.Create(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		var item struct {
+			Value     string
+			Signature string
+		}
+		err = mstore.db.Select("value, signature").Where("id=?", sid).Scan(&item).Error
+		So(err, ShouldBeNil)
+		expect, _ := hmacSign(sid, []byte(item.Value))
+		So(item.Signature, ShouldEqual, expect)
+
+		newsid := newSid()
+		defer mstore.Delete(ctx, newsid)
+		_, err = mstore.Refresh(ctx, sid, newsid, expired)
+		So(err, ShouldBeNil)
+
+		err = mstore.db.Select("value, signature").Where("id=?", newsid).Scan(&item).Error
+		So(err, ShouldBeNil)
+		expect, _ = hmacSign(newsid, []byte(item.Value))
+		So(item.Signature, ShouldEqual, expect)
+	})
+}
@@ -0,0 +1,21 @@
+//go:build go1.21
+// +build go1.21
+
+package gorm
+
+import (
+	"log/slog"
+)
+
+// WithSlog Logs the operations and GC runs to logger as structured records
+// of the op, table, sid_hash, duration, rows and error attributes, see Config.Logger,
+// a nil logger disables the logging
+func WithSlog(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger == nil {
+			o.cfg.Logger = nil
+			return
+		}
+		o.cfg.Logger = logger
+	}
+}
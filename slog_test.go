@@ -0,0 +1,62 @@
+//go:build go1.21
+// +build go1.21
+
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithSlog(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	mstore, err := New(db, WithConfig(Config{NoBackground: true}), WithTableName("session_with_slog"), WithSlog(logger))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test structured records of a failing operation", t, func() {
+		ctx := context.Background()
+		jsonMarshal = func(interface{}) ([]byte, error) { return nil, errors.New("marshal failed") }
+		defer func() { jsonMarshal = json.Marshal }()
+
+		buf.Reset()
+		store, err := mstore.Create(ctx, newSid(), 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldNotBeNil)
+
+		var entry map[string]interface{}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(json.Unmarshal([]byte(lines[len(lines)-1]), &entry), ShouldBeNil)
+		So(entry["level"], ShouldEqual, "ERROR")
+		So(entry["op"], ShouldEqual, "save")
+		So(entry["table"], ShouldEqual, "session_with_slog")
+		So(entry["error"], ShouldEqual, "marshal failed")
+		So(entry["duration"], ShouldNotBeNil)
+	})
+
+	Convey("Test a nil slog logger disables the logging", t, func() {
+		var o options
+		WithSlog(nil)(&o)
+		So(o.cfg.Logger, ShouldBeNil)
+	})
+}
@@ -0,0 +1,89 @@
+package gorm
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// statsItem Snapshot of the sessions of a sid prefix, the created and deleted counts
+// are relative to the previous snapshot of the prefix, sessions created and deleted
+// between two snapshots are not counted
+type statsItem struct {
+	ID         uint      `gorm:"column:id;primary_key;"`
+	SIDPrefix  string    `gorm:"column:sid_prefix;size:255;default:'';"`
+	RecordedAt time.Time `gorm:"column:recorded_at;"`
+	Total      int       `gorm:"column:total;"`
+	Active     int       `gorm:"column:active;"`
+	Created    int       `gorm:"column:created;"`
+	Deleted    int       `gorm:"column:deleted;"`
+	AvgSize    float64   `gorm:"column:avg_size;"`
+}
+
+// statsTable Returns the name of the statistics table of a session table
+func statsTable(tableName string) string {
+	return tableName + "_stats"
+}
+
+// stats Returns the db of the statistics table
+func (s *ManagerStore) stats() *gorm.DB {
+	return s.db.Table(statsTable(s.tableName))
+}
+
+// scopedStats Returns the db of the snapshots of the store's sid prefix,
+// the stores of other prefixes sharing the table record their own
+func (s *ManagerStore) scopedStats() *gorm.DB {
+	return s.stats().Where("sid_prefix=?", s.idPrefix)
+}
+
+// recordStats Writes a snapshot of the sessions of the store's sid prefix
+func (s *ManagerStore) recordStats() error {
+	wg := &s.root().wg
+	wg.Add(1)
+	defer wg.Done()
+
+	var last statsItem
+	err := s.scopedStats().Order("recorded_at DESC").First(&last).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	now := time.Now()
+	item := statsItem{SIDPrefix: s.idPrefix, RecordedAt: now}
+	db := s.scoped()
+	if err := db.Count(&item.Total).Error; err != nil {
+		return err
+	}
+	if err := db.Where("expired_at>?", now).Count(&item.Active).Error; err != nil {
+		return err
+	}
+
+	if !last.RecordedAt.IsZero() {
+		err := db.Where("created_at>? AND created_at<=?", last.RecordedAt, now).Count(&item.Created).Error
+		if err != nil {
+			return err
+		}
+		if deleted := last.Total + item.Created - item.Total; deleted > 0 {
+			item.Deleted = deleted
+		}
+	}
+
+	length := "LENGTH"
+	if s.db.Dialect().GetName() == "mssql" {
+		length = "LEN"
+	}
+	values := db
+	if s.separateValues {
+		values = s.scope(s.values())
+	}
+	var avg sql.NullFloat64
+	err = values.Select(fmt.Sprintf("AVG(%s(value))", length)).Row().Scan(&avg)
+	if err != nil {
+		return err
+	}
+	item.AvgSize = avg.Float64
+
+	return s.stats().Create(&item).Error
+}
@@ -0,0 +1,91 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecordStats(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_snapshot", EnableStats: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test recording snapshots of the session table", t, func() {
+		ctx := context.Background()
+		sids := []string{newSid(), newSid()}
+		defer mstore.Delete(ctx, sids[1])
+
+		create := func(sid string) {
+			store, err := mstore.Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+		}
+
+		create(sids[0])
+		So(mstore.recordStats(), ShouldBeNil)
+		time.Sleep(10 * time.Millisecond)
+
+		create(sids[1])
+		So(mstore.Delete(ctx, sids[0]), ShouldBeNil)
+		So(mstore.recordStats(), ShouldBeNil)
+
+		var items []statsItem
+		So(mstore.scopedStats().Order("recorded_at DESC").Limit(2).Find(&items).Error, ShouldBeNil)
+		So(items, ShouldHaveLength, 2)
+		So(items[0].Created, ShouldEqual, 1)
+		So(items[0].Deleted, ShouldEqual, 1)
+		So(items[0].Total, ShouldEqual, items[1].Total)
+		So(items[0].AvgSize, ShouldBeGreaterThan, 0)
+	})
+}
+
+func TestRecordStatsSIDPrefix(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	stores := make([]*ManagerStore, 2)
+	for i, prefix := range []string{"app_1:", "app_2:"} {
+		mstore, err := NewManagerStore(Config{TableName: "session_snapshot_shared", SIDPrefix: prefix, EnableStats: true}, "sqlite3", dsn)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		defer mstore.Close()
+		stores[i] = mstore
+	}
+
+	Convey("Test recording the snapshots per sid prefix", t, func() {
+		ctx := context.Background()
+		So(stores[0].db.Delete(nil).Error, ShouldBeNil)
+		So(stores[0].stats().Delete(nil).Error, ShouldBeNil)
+
+		for i := 0; i < 2; i++ {
+			sid := newSid()
+			defer stores[0].Delete(ctx, sid)
+			store, err := stores[0].Create(ctx, sid, 60)
+			So(err, ShouldBeNil)
+			So(store.Save(), ShouldBeNil)
+		}
+		for _, mstore := range stores {
+			So(mstore.recordStats(), ShouldBeNil)
+		}
+
+		var items []statsItem
+		So(stores[0].scopedStats().Find(&items).Error, ShouldBeNil)
+		So(items, ShouldHaveLength, 1)
+		So(items[0].SIDPrefix, ShouldEqual, "app_1:")
+		So(items[0].Total, ShouldEqual, 2)
+
+		So(stores[1].scopedStats().Find(&items).Error, ShouldBeNil)
+		So(items, ShouldHaveLength, 1)
+		So(items[0].SIDPrefix, ShouldEqual, "app_2:")
+		So(items[0].Total, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,106 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// GCStatus The state of the GC of a store
+type GCStatus struct {
+	Runs     int64         `json:"runs"`        // Number of completed GC runs
+	LastRun  time.Time     `json:"last_run"`    // Start of the last completed run
+	Duration time.Duration `json:"duration_ns"` // Duration of the last completed run
+	Deleted  int64         `json:"deleted"`     // Sessions deleted by the last completed run
+	Running  bool          `json:"running"`     // Whether a run is in progress
+	Leader   bool          `json:"leader"`      // Whether this instance runs GC with Config.CoordinateGC
+	Backlog  int64         `json:"backlog"`     // Expired sessions waiting for the next run
+}
+
+// GCResult The outcome of a GC run passed to Config.OnGC
+type GCResult struct {
+	Started  time.Time
+	Duration time.Duration
+	Deleted  int64 // Sessions deleted by the run
+	Err      error // First error of the run, the run continues after errors
+}
+
+// StoreStatus The GC status, session counts and pool statistics served by StatusHandler
+type StoreStatus struct {
+	GC       GCStatus         `json:"gc"`
+	Total    int64            `json:"total"`
+	Active   int64            `json:"active"`
+	Pool     sql.DBStats      `json:"pool"`
+	Codec    map[string]int64 `json:"codec_errors"`
+	Content  *ContentReport   `json:"content,omitempty"`
+	Recorded time.Time        `json:"recorded_at"`
+}
+
+// GCStatus Returns the state of the GC, the backlog is counted when it is called
+func (s *ManagerStore) GCStatus(ctx context.Context) (GCStatus, error) {
+	if s.isClosed() {
+		return GCStatus{}, ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return GCStatus{}, err
+	}
+
+	root := s.root()
+	root.gcMu.Lock()
+	status := root.gcStatus
+	status.Leader = !root.coordinateGC || root.gcLock != nil
+	root.gcMu.Unlock()
+	status.Running = atomic.LoadInt32(&root.gcRunning) == 1
+
+	err = s.scoped().Where("expired_at<=?", time.Now()).Count(&status.Backlog).Error
+	return status, err
+}
+
+// Status Returns the GC status, the number of stored and active sessions
+// and the connection pool statistics
+func (s *ManagerStore) Status(ctx context.Context) (*StoreStatus, error) {
+	gc, err := s.GCStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err = s.forContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &StoreStatus{
+		GC:       gc,
+		Pool:     s.PoolStats(),
+		Codec:    s.CodecErrors(),
+		Content:  s.ContentStats(),
+		Recorded: time.Now(),
+	}
+	err = s.scoped().Count(&status.Total).Error
+	if err != nil {
+		return nil, err
+	}
+	status.Active = status.Total - gc.Backlog
+	return status, nil
+}
+
+// StatusHandler Returns an http.Handler serving Status as JSON,
+// e.g. to be mounted under an internal admin mux, it does not authenticate requests
+func (s *ManagerStore) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := s.Status(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+}
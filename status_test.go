@@ -0,0 +1,53 @@
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStatusHandler(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_status", NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test serving the GC status", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Delete(nil).Error, ShouldBeNil)
+		for _, expired := range []int64{1, 1, 60} {
+			store, err := mstore.Create(ctx, newSid(), expired)
+			So(err, ShouldBeNil)
+			store.Set("foo", "bar")
+			So(store.Save(), ShouldBeNil)
+		}
+		time.Sleep(time.Millisecond * 1500)
+
+		var status StoreStatus
+		rec := httptest.NewRecorder()
+		mstore.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		So(json.Unmarshal(rec.Body.Bytes(), &status), ShouldBeNil)
+		So(status.GC.Runs, ShouldEqual, 0)
+		So(status.GC.Backlog, ShouldEqual, 2)
+		So(status.Total, ShouldEqual, 3)
+		So(status.Active, ShouldEqual, 1)
+
+		So(mstore.GC(ctx), ShouldBeNil)
+		gc, err := mstore.GCStatus(ctx)
+		So(err, ShouldBeNil)
+		So(gc.Runs, ShouldEqual, 1)
+		So(gc.Deleted, ShouldEqual, 2)
+		So(gc.Backlog, ShouldEqual, 0)
+		So(gc.LastRun.IsZero(), ShouldBeFalse)
+	})
+}
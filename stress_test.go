@@ -0,0 +1,155 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	stressWorkers = 200
+)
+
+func newStressStore(t *testing.T, tableName string) *ManagerStore {
+	dsn := os.TempDir() + "/gorm.db"
+	// sqlite allows a single writer, queue the workers on one connection
+	mstore, err := NewManagerStore(Config{TableName: tableName, MaxOpenConns: 1}, "sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return mstore
+}
+
+func TestStressManagerStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	mstore := newStressStore(t, "session_stress")
+	defer mstore.Close()
+
+	Convey("Test concurrent session lifecycles against one store", t, func() {
+		ctx := context.Background()
+		errs := make(chan error, stressWorkers)
+		done := make(chan struct{})
+
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					mstore.clean()
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for i := 0; i < stressWorkers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- stressLifecycle(ctx, mstore, fmt.Sprintf("%s_%d", newSid(), i))
+			}(i)
+		}
+		wg.Wait()
+		close(done)
+		close(errs)
+
+		for err := range errs {
+			So(err, ShouldBeNil)
+		}
+	})
+}
+
+func stressLifecycle(ctx context.Context, mstore *ManagerStore, sid string) error {
+	store, err := mstore.Create(ctx, sid, 60)
+	if err != nil {
+		return err
+	}
+	store.Set("sid", sid)
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	store, err = mstore.Update(ctx, sid, 60)
+	if err != nil {
+		return err
+	} else if v, _ := store.Get("sid"); v != sid {
+		return fmt.Errorf("update %s: lost value, got %v", sid, v)
+	}
+	store.Set("n", 1)
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	newsid := sid + "_refreshed"
+	store, err = mstore.Refresh(ctx, sid, newsid, 60)
+	if err != nil {
+		return err
+	} else if v, _ := store.Get("sid"); v != sid {
+		return fmt.Errorf("refresh %s: lost value, got %v", sid, v)
+	}
+
+	exists, err := mstore.Check(ctx, sid)
+	if err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("refresh %s: old session still exists", sid)
+	}
+	return mstore.Delete(ctx, newsid)
+}
+
+func TestStressStoreValues(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	mstore := newStressStore(t, "session_stress")
+	defer mstore.Close()
+
+	Convey("Test concurrent mutations of one session", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(store.Save(), ShouldBeNil)
+
+		errs := make(chan error, stressWorkers)
+		var wg sync.WaitGroup
+		for i := 0; i < stressWorkers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := fmt.Sprintf("key_%d", i)
+				store.Set(key, i)
+				store.Get(key)
+				if i%2 == 0 {
+					store.Delete(key)
+				}
+				errs <- store.Save()
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			So(err, ShouldBeNil)
+		}
+
+		// concurrent saves may land out of order, persist the final state
+		So(store.Save(), ShouldBeNil)
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		for i := 0; i < stressWorkers; i++ {
+			_, ok := store.Get(fmt.Sprintf("key_%d", i))
+			So(ok, ShouldEqual, i%2 == 1)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTableNotAllowed Returned for a table selected by WithTable that is not listed in Config.Tables
+var ErrTableNotAllowed = errors.New("gorm session: table is not listed in Config.Tables")
+
+type tableKey struct{}
+
+// WithTable Returns a context that makes the store operations run against
+// the table name instead of the configured one, which must be listed in Config.Tables,
+// the table is created on first use. GC only cleans the configured table.
+func WithTable(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tableKey{}, name)
+}
+
+// forContext Returns the store for the table and the consistency selected by ctx
+func (s *ManagerStore) forContext(ctx context.Context) (*ManagerStore, error) {
+	if ctx == nil {
+		return s, nil
+	} else if err := checkBudget(ctx); err != nil {
+		return nil, err
+	}
+
+	store, err := s.forTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	store = store.bind(ctx)
+	if store.missing == nil || consistencyOf(ctx) != ConsistencyStrong {
+		return store, nil
+	}
+	if store == s {
+		store = s.withDB(s.db)
+	}
+	store.strong = true
+	return store, nil
+}
+
+// forTable Returns the store for the table selected by ctx
+func (s *ManagerStore) forTable(ctx context.Context) (*ManagerStore, error) {
+	name, ok := ctx.Value(tableKey{}).(string)
+	if !ok || name == "" || name == s.tableName {
+		return s, nil
+	}
+
+	if s.strict {
+		if err := checkIdentifier(name); err != nil {
+			return nil, err
+		}
+	}
+
+	root := s.root()
+	if !root.allowed[name] {
+		return nil, ErrTableNotAllowed
+	}
+	root.tablesMu.Lock()
+	defer root.tablesMu.Unlock()
+
+	store := s.withDB(s.db.Table(name))
+	store.tableName = name
+
+	missing, ok := root.tables[name]
+	if !ok {
+		if err := store.initTable(); err != nil {
+			return nil, err
+		}
+		if root.newCache != nil {
+			missing = root.newCache()
+		}
+		root.tables[name] = missing
+	}
+	store.missing = missing
+	return store, nil
+}
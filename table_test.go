@@ -0,0 +1,50 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithTable(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_default", Tables: []string{"session_brand"}}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test selecting the table per request", t, func() {
+		ctx := WithTable(context.Background(), "session_brand")
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		exists, err = mstore.Check(context.Background(), sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		store, err = mstore.Update(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+
+		// the tables that are not listed are neither created nor used
+		_, err = mstore.Check(WithTable(context.Background(), "session_unlisted"), sid)
+		So(err, ShouldEqual, ErrTableNotAllowed)
+		So(mstore.db.HasTable("session_unlisted"), ShouldBeFalse)
+		So(mstore.tables, ShouldNotContainKey, "session_unlisted")
+	})
+}
@@ -0,0 +1,92 @@
+package gorm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TableTemplateData The placeholders of a Config.TableTemplates template
+type TableTemplateData struct {
+	Table   string            // Quoted name of the session table
+	Columns string            // Column definitions and primary key gorm would create, comma separated
+	Types   map[string]string // Data type of every column by name, e.g. {{index .Types "value"}}
+	Size    int               // Size of the value column (default 2048)
+}
+
+// parseTableTemplates Parses the CREATE TABLE templates of cfg by dialect
+func parseTableTemplates(templates map[string]string) (map[string]*template.Template, error) {
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	parsed := make(map[string]*template.Template, len(templates))
+	for dialect, text := range templates {
+		if _, ok := gorm.GetDialect(dialect); !ok {
+			return nil, fmt.Errorf("gorm session: table template of unknown dialect %s", dialect)
+		}
+
+		tpl, err := template.New(dialect).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("gorm session: table template of %s: %v", dialect, err)
+		}
+		parsed[dialect] = tpl
+	}
+	return parsed, nil
+}
+
+// renderTableTemplate Returns the CREATE TABLE statement of model on the table of db
+// rendered from tpl, with the columns gorm's CreateTable would define
+func renderTableTemplate(db *gorm.DB, tpl *template.Template, model interface{}, valueSize int) (string, error) {
+	scope := db.NewScope(model)
+	data := TableTemplateData{
+		Table: scope.QuotedTableName(),
+		Types: make(map[string]string),
+		Size:  valueSize,
+	}
+	if data.Size <= 0 {
+		data.Size = defaultValueColumnSize
+	}
+
+	var columns, primaryKeys []string
+	for _, field := range scope.GetModelStruct().StructFields {
+		if !field.IsNormal {
+			continue
+		}
+
+		typ := db.Dialect().DataTypeOf(field)
+		data.Types[field.DBName] = typ
+		columns = append(columns, scope.Quote(field.DBName)+" "+typ)
+		if field.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, scope.Quote(field.DBName))
+		}
+	}
+	if len(primaryKeys) > 0 {
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ",")))
+	}
+	data.Columns = strings.Join(columns, ",")
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// createTable Creates the session table with the template of the dialect,
+// or with gorm's CreateTable if there is none
+func (s *ManagerStore) createTable(model interface{}) error {
+	tpl, ok := s.tableTemplates[s.db.Dialect().GetName()]
+	if !ok {
+		return s.db.CreateTable(model).Error
+	}
+
+	ddl, err := renderTableTemplate(s.db, tpl, model, s.valueSize)
+	if err != nil {
+		return err
+	}
+	return s.db.Exec(ddl).Error
+}
@@ -0,0 +1,53 @@
+package gorm
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTableTemplates(t *testing.T) {
+	db, err := gorm.Open("sqlite3", os.TempDir()+"/gorm.db")
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	db.DropTableIfExists("session_template")
+
+	Convey("Test creating the session table from a dialect template", t, func() {
+		mstore, err := NewStoreWithConfig(db, Config{
+			TableName:    "session_template",
+			NoBackground: true,
+			TableTemplates: map[string]string{
+				"sqlite3": "CREATE TABLE {{.Table}} ({{.Columns}}) WITHOUT ROWID",
+			},
+		})
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		var ddl string
+		row := db.Raw("SELECT sql FROM sqlite_master WHERE type='table' AND name=?", "session_template").Row()
+		So(row.Scan(&ddl), ShouldBeNil)
+		So(ddl, ShouldEndWith, "WITHOUT ROWID")
+		So(mstore.db.HasTable("session_template"), ShouldBeTrue)
+
+		out, err := GenerateDDL("postgres", Config{
+			TableName: "session_template",
+			TableTemplates: map[string]string{
+				"postgres": `CREATE TABLE {{.Table}} ({{.Columns}}) TABLESPACE fast; -- value {{index .Types "value"}} {{.Size}}`,
+			},
+		})
+		So(err, ShouldBeNil)
+		So(out, ShouldContainSubstring, `CREATE TABLE "session_template" ("id" varchar(255)`)
+		So(out, ShouldContainSubstring, `PRIMARY KEY ("id")) TABLESPACE fast; -- value varchar(2048) 2048`)
+		So(strings.Count(out, "CREATE TABLE"), ShouldEqual, 1)
+
+		_, err = GenerateDDL("postgres", Config{TableTemplates: map[string]string{"postgres": "{{.Table"}})
+		So(err, ShouldNotBeNil)
+		_, err = GenerateDDL("postgres", Config{TableTemplates: map[string]string{"oracle": "{{.Table}}"}})
+		So(err, ShouldNotBeNil)
+	})
+}
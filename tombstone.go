@@ -0,0 +1,66 @@
+package gorm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrSessionRevoked Returned by Save for a session deleted within Config.TombstoneTTL,
+// so a save in flight during a logout does not bring the session back
+var ErrSessionRevoked = errors.New("gorm session: session was revoked")
+
+// tombstoneItem The marker of a deleted session
+type tombstoneItem struct {
+	ID        string    `gorm:"column:id;size:255;primary_key;"`
+	ExpiredAt time.Time `gorm:"column:expired_at;"`
+}
+
+// tombstoneTable Returns the name of the tombstone table of a session table
+func tombstoneTable(tableName string) string {
+	return tableName + "_tombstones"
+}
+
+// tombstones Returns the db of the tombstone table
+func (s *ManagerStore) tombstones() *gorm.DB {
+	return s.db.Table(tombstoneTable(s.tableName))
+}
+
+// bury Records the tombstone of the row key, before the row is deleted so that
+// a save that wrote the row after the delete sees it
+func (s *ManagerStore) bury(key string) error {
+	if s.tombstoneTTL <= 0 {
+		return nil
+	}
+	return s.tombstones().Save(&tombstoneItem{ID: key, ExpiredAt: time.Now().Add(s.tombstoneTTL)}).Error
+}
+
+// revoked Reports whether a row of keys has a live tombstone
+func (s *ManagerStore) revoked(keys ...string) (bool, error) {
+	if s.tombstoneTTL <= 0 {
+		return false, nil
+	}
+
+	var count int
+	err := s.tombstones().Where("id IN (?) AND expired_at>?", keys, time.Now()).Count(&count).Error
+	return count > 0, err
+}
+
+// checkRevoked Deletes the row key again if it was written after its session was deleted
+func (s *ManagerStore) checkRevoked(key string) error {
+	ok, err := s.revoked(key)
+	if err != nil || !ok {
+		return err
+	}
+
+	if _, err := s.deleteSession(key); err != nil {
+		return err
+	}
+	return ErrSessionRevoked
+}
+
+// cleanTombstones Deletes the expired tombstones
+func (s *ManagerStore) cleanTombstones() error {
+	return s.tombstones().Where("expired_at<=?", time.Now()).Delete(&tombstoneItem{}).Error
+}
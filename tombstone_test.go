@@ -0,0 +1,110 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTombstones(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_tombstone", TombstoneTTL: time.Minute, NoBackground: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test a save in flight during a delete does not resurrect the session", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("user", "foo")
+		So(store.Save(), ShouldBeNil)
+
+		inflight, err := mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+
+		inflight.Set("cart", "bar")
+		So(inflight.Save(), ShouldEqual, ErrSessionRevoked)
+		ok, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		err = mstore.SaveMany(ctx, []*Store{inflight.(*Store)}, 0)
+		So(err, ShouldEqual, ErrSessionRevoked)
+		ok, err = mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Test the old sid of a refresh is revoked", t, func() {
+		ctx := context.Background()
+		oldsid, sid := newSid(), newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, oldsid, 60)
+		So(err, ShouldBeNil)
+		store.Set("user", "foo")
+		So(store.Save(), ShouldBeNil)
+		_, err = mstore.Refresh(ctx, oldsid, sid, 60)
+		So(err, ShouldBeNil)
+
+		So(store.Save(), ShouldEqual, ErrSessionRevoked)
+	})
+
+	Convey("Test GC removes the expired tombstones", t, func() {
+		So(mstore.tombstones().Save(&tombstoneItem{ID: "expired", ExpiredAt: time.Now().Add(-time.Second)}).Error, ShouldBeNil)
+		_, err := mstore.clean()
+		So(err, ShouldBeNil)
+
+		var count int
+		So(mstore.tombstones().Where("id=?", "expired").Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+}
+
+func TestTombstonesEmptyDelete(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{
+		TableName:    "session_tombstone_empty",
+		TombstoneTTL: time.Minute,
+		EmptyPolicy:  EmptyDelete,
+		NoBackground: true,
+	}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test emptying a session does not revoke its sid", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("user", "foo")
+		So(store.Save(), ShouldBeNil)
+
+		store.Delete("user")
+		So(store.Save(), ShouldBeNil)
+		ok, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		store.Set("user", "bar")
+		So(store.Save(), ShouldBeNil)
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		user, _ := store.Get("user")
+		So(user, ShouldEqual, "bar")
+	})
+}
@@ -0,0 +1,53 @@
+package gorm
+
+import (
+	"context"
+)
+
+// Tracer Starts the spans of the operations, e.g. an adapter of an OpenTelemetry
+// trace.Tracer that turns the key/value pairs into span attributes
+type Tracer interface {
+	// Start Starts a span named name as a child of the span of ctx, with the
+	// "op", "table" and "dialect" attributes as key/value pairs in attrs
+	Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, Span)
+}
+
+// Span A span started by a Tracer
+type Span interface {
+	// End Ends the span with the rows read, written or deleted by the operation
+	// and its error, nil on success
+	End(rows int64, err error)
+}
+
+// opSpan The span of an operation and the rows it counted, nil without a Tracer
+type opSpan struct {
+	span Span
+	rows int64
+}
+
+// startSpan Starts the span of op as a child of the span of ctx, nil without a Tracer
+func (s *ManagerStore) startSpan(ctx context.Context, op string) *opSpan {
+	if s.tracer == nil {
+		return nil
+	} else if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, span := s.tracer.Start(ctx, "gorm session "+op,
+		"op", op, "table", s.tableName, "dialect", s.db.Dialect().GetName())
+	return &opSpan{span: span}
+}
+
+// setRows Sets the rows counted by the operation
+func (o *opSpan) setRows(rows int64) {
+	if o != nil {
+		o.rows = rows
+	}
+}
+
+// end Ends the span with the error of the operation in *err
+func (o *opSpan) end(err *error) {
+	if o != nil {
+		o.span.End(o.rows, *err)
+	}
+}
@@ -0,0 +1,92 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	tracer *testTracer
+	name   string
+	parent interface{}
+	attrs  []interface{}
+}
+
+func (s *testSpan) End(rows int64, err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.ended = append(s.tracer.ended, testEndedSpan{s, rows, err})
+}
+
+type testEndedSpan struct {
+	*testSpan
+	rows int64
+	err  error
+}
+
+type testTracer struct {
+	mu    sync.Mutex
+	ended []testEndedSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, Span) {
+	span := &testSpan{tracer: t, name: name, parent: ctx.Value(testSpanKey{}), attrs: attrs}
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (t *testTracer) last() testEndedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ended[len(t.ended)-1]
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_trace", Tracer: tracer}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test spans around the operations", t, func() {
+		ctx := context.WithValue(context.Background(), testSpanKey{}, "request")
+		sid := newSid()
+
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		span := tracer.last()
+		So(span.name, ShouldEqual, "gorm session create")
+		So(span.parent, ShouldEqual, "request")
+		So(span.attrs, ShouldResemble, []interface{}{"op", "create", "table", "session_trace", "dialect", "sqlite3"})
+
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		So(tracer.last().name, ShouldEqual, "gorm session save")
+		So(tracer.last().rows, ShouldEqual, 1)
+
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(tracer.last().rows, ShouldEqual, 1)
+
+		So(mstore.Delete(ctx, sid), ShouldBeNil)
+		So(tracer.last().name, ShouldEqual, "gorm session delete")
+		So(tracer.last().rows, ShouldEqual, 1)
+		So(tracer.last().err, ShouldBeNil)
+
+		_, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		So(tracer.last().rows, ShouldEqual, 0)
+
+		mstore.runGC()
+		So(tracer.last().name, ShouldEqual, "gorm session gc")
+		So(tracer.last().parent, ShouldBeNil)
+	})
+}
@@ -0,0 +1,70 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// WithTransaction Runs fn inside one database transaction,
+// every operation of the store passed to fn (and of the sessions it returns)
+// uses the same connection, so a load-modify-save cycle is atomic.
+// The transaction is committed if fn returns nil and rolled back otherwise,
+// tx must not be used after fn returns.
+func (s *ManagerStore) WithTransaction(ctx context.Context, fn func(tx *ManagerStore) error) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	db := s.db.BeginTx(ctx, nil)
+	if err := db.Error; err != nil {
+		return err
+	}
+	defer db.RollbackUnlessCommitted()
+
+	tx := s.withDB(db)
+	// GC must not run on the connection of the transaction
+	tx.gcProbability = 0
+	// sqlite locks the whole database for a write transaction
+	// and does not support row locks
+	tx.forUpdate = db.Dialect().GetName() != "sqlite3"
+	// the lifecycle hooks run once the changes are committed
+	tx.txHooks = new([]func())
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := db.Commit().Error; err != nil {
+		return err
+	}
+	for _, hook := range *tx.txHooks {
+		hook()
+	}
+	return nil
+}
+
+// atomically Runs fn on a copy of the store inside a transaction, or on the store itself
+// if it runs in a transaction already, e.g. to write the session row and its value together
+func (s *ManagerStore) atomically(ctx context.Context, fn func(tx *ManagerStore) error) error {
+	if _, ok := s.db.CommonDB().(*sql.Tx); ok {
+		return fn(s)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	db := s.db.BeginTx(ctx, nil)
+	if err := db.Error; err != nil {
+		return err
+	}
+	defer db.RollbackUnlessCommitted()
+
+	if err := fn(s.withDB(db)); err != nil {
+		return err
+	}
+	return db.Commit().Error
+}
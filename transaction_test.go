@@ -0,0 +1,56 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithTransaction(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_tx"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test load-modify-save inside a transaction", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		err := mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			store, err := tx.Update(ctx, sid, expired)
+			if err != nil {
+				return err
+			}
+			store.Set("foo", "bar")
+			return store.Save()
+		})
+		So(err, ShouldBeNil)
+
+		errRollback := errors.New("rollback")
+		err = mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			store, err := tx.Update(ctx, sid, expired)
+			if err != nil {
+				return err
+			}
+			store.Set("foo", "baz")
+			if err := store.Save(); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		So(err, ShouldEqual, errRollback)
+
+		store, err := mstore.Update(ctx, sid, expired)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+	})
+}
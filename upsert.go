@@ -0,0 +1,187 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// upsert Inserts the session row, or updates fields of the existing row, in one statement,
+// it reports false without running anything if the dialect has no upsert
+func (s *ManagerStore) upsert(item *SessionItem, fields map[string]interface{}) (bool, error) {
+	var conflict string
+	switch s.db.Dialect().GetName() {
+	case "mysql":
+		conflict = "ON DUPLICATE KEY UPDATE %s"
+	case "postgres", "sqlite3":
+		conflict = "ON CONFLICT (id) DO UPDATE SET %s"
+	default:
+		return false, nil
+	}
+
+	columns := []string{"id", "created_at", "expired_at"}
+	args := []interface{}{item.ID, item.CreatedAt, item.ExpiredAt}
+	if !s.separateValues {
+		columns = append(columns, "value")
+		args = append(args, item.Value)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	scope := s.db.NewScope(nil)
+	var assignments []string
+	var updateArgs []interface{}
+	for _, k := range keys {
+		if !containsString(columns, k) {
+			columns = append(columns, k)
+			args = append(args, fields[k])
+		}
+		assignments = append(assignments, scope.Quote(k)+"=?")
+		updateArgs = append(updateArgs, fields[k])
+	}
+	if len(assignments) == 0 {
+		// nothing to update, the id is assigned to itself to keep the statement valid
+		assignments = append(assignments, scope.Quote("id")+"="+scope.Quote("id"))
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = scope.Quote(column)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+	query := fmt.Sprintf("INSERT INTO %v (%s) VALUES (%s) "+conflict,
+		scope.QuotedTableName(), strings.Join(quoted, ","), placeholders, strings.Join(assignments, ","))
+
+	err := s.db.Exec(query, append(args, updateArgs...)...).Error
+	return true, err
+}
+
+// upsertMany Inserts or updates the session rows in one statement, every row must come
+// with the same field names, it reports false without running anything if the dialect has no upsert
+func (s *ManagerStore) upsertMany(items []*SessionItem, fields []map[string]interface{}) (bool, error) {
+	scope := s.db.NewScope(nil)
+	var conflict string
+	var assign func(column string) string
+	switch s.db.Dialect().GetName() {
+	case "mysql":
+		conflict = "ON DUPLICATE KEY UPDATE %s"
+		assign = func(column string) string { return column + "=VALUES(" + column + ")" }
+	case "postgres", "sqlite3":
+		conflict = "ON CONFLICT (id) DO UPDATE SET %s"
+		assign = func(column string) string { return column + "=excluded." + column }
+	default:
+		return false, nil
+	}
+	if len(items) == 0 {
+		return true, nil
+	}
+
+	columns := []string{"id", "created_at", "expired_at"}
+	if !s.separateValues {
+		columns = append(columns, "value")
+	}
+	base := len(columns)
+
+	keys := make([]string, 0, len(fields[0]))
+	for k := range fields[0] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var assignments []string
+	for _, k := range keys {
+		if !containsString(columns, k) {
+			columns = append(columns, k)
+		}
+		assignments = append(assignments, assign(scope.Quote(k)))
+	}
+	if len(assignments) == 0 {
+		assignments = append(assignments, scope.Quote("id")+"="+scope.Quote("id"))
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = scope.Quote(column)
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+
+	rows := make([]string, len(items))
+	var args []interface{}
+	for i, item := range items {
+		rows[i] = placeholders
+		args = append(args, item.ID, item.CreatedAt, item.ExpiredAt)
+		if !s.separateValues {
+			args = append(args, item.Value)
+		}
+		for _, column := range columns[base:] {
+			args = append(args, fields[i][column])
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %v (%s) VALUES %s "+conflict,
+		scope.QuotedTableName(), strings.Join(quoted, ","), strings.Join(rows, ","), strings.Join(assignments, ","))
+
+	err := s.db.Exec(query, args...).Error
+	return true, err
+}
+
+// insertOrUpdate Inserts the session row if it does not exist and updates fields,
+// for dialects without an upsert
+func (s *ManagerStore) insertOrUpdate(item *SessionItem, fields map[string]interface{}) error {
+	exists, err := s.exists(s.db, item.ID)
+	if err != nil {
+		return err
+	} else if !exists {
+		err := s.createItem(item)
+		if err != nil {
+			return err
+		}
+	} else if s.separateValues {
+		err := s.writeValue(item.ID, item.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(fields) > 0 {
+		result := s.db.Where("id=?", item.ID).Updates(fields)
+		if err := result.Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeItem Inserts the session row or updates fields of the existing row,
+// with SeparateValues the row and its value are written in one transaction
+func (s *ManagerStore) writeItem(ctx context.Context, item *SessionItem, fields map[string]interface{}) error {
+	write := func(tx *ManagerStore) error {
+		ok, err := tx.upsert(item, fields)
+		if err != nil {
+			return err
+		} else if !ok {
+			return tx.insertOrUpdate(item, fields)
+		} else if tx.separateValues {
+			return tx.writeValue(item.ID, item.Value)
+		}
+		return nil
+	}
+
+	if !s.separateValues {
+		return write(s)
+	}
+	return s.atomically(ctx, write)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpsert(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_upsert", Signer: SignerFunc(hmacSign)}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test concurrent first saves of a session", t, func() {
+		ctx := context.Background()
+		sid := newSid()
+		defer mstore.Delete(ctx, sid)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				store, err := mstore.Create(ctx, sid, 60)
+				if err != nil {
+					errs <- err
+					return
+				}
+				store.Set("n", i)
+				errs <- store.Save()
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			So(err, ShouldBeNil)
+		}
+
+		var item struct {
+			SessionItem
+			Signature string
+		}
+		So(mstore.db.Where("id=?", sid).First(&item).Error, ShouldBeNil)
+		signature, err := hmacSign(sid, []byte(item.Value))
+		So(err, ShouldBeNil)
+		So(item.Signature, ShouldEqual, signature)
+	})
+
+	Convey("Test saving without an upsert", t, func() {
+		sid := newSid()
+		defer mstore.Delete(context.Background(), sid)
+
+		item := &SessionItem{ID: sid, Value: `{"foo":"bar"}`, CreatedAt: time.Now(), ExpiredAt: mstore.GetExpired(60)}
+		So(mstore.insertOrUpdate(item, map[string]interface{}{"value": item.Value}), ShouldBeNil)
+		So(mstore.insertOrUpdate(item, map[string]interface{}{"value": `{"foo":"baz"}`}), ShouldBeNil)
+
+		store, err := mstore.Update(context.Background(), sid, 60)
+		So(err, ShouldBeNil)
+		foo, _ := store.Get("foo")
+		So(foo, ShouldEqual, "baz")
+	})
+}
@@ -0,0 +1,153 @@
+package gorm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// hllPrecision The number of index bits of a sketch, 2^12 registers
+	// estimate with a standard error of about 1.6%
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+
+	defaultUniqueUsersInterval = time.Hour
+)
+
+// ErrUniqueUsersDisabled Returned by UniqueUsers if Config.UniqueUsersKey is not set
+var ErrUniqueUsersDisabled = errors.New("gorm session: unique users are not tracked")
+
+// usersItem The HyperLogLog sketch of the users an instance saw active in an interval,
+// the sketches of all instances are merged when estimating
+type usersItem struct {
+	Interval  time.Time `gorm:"column:interval_start;primary_key;"`
+	Instance  string    `gorm:"column:instance;size:64;primary_key;"`
+	Registers []byte    `gorm:"column:registers;size:4096;"`
+}
+
+// usersTable Returns the name of the unique users table of a session table
+func usersTable(tableName string) string {
+	return tableName + "_users"
+}
+
+// users Returns the db of the unique users table
+func (s *ManagerStore) users() *gorm.DB {
+	return s.db.Table(usersTable(s.tableName))
+}
+
+// hllAdd Adds the hash of a user id to the registers of a sketch
+func hllAdd(registers []byte, userID string) {
+	sum := sha256.Sum256([]byte(userID))
+	hash := binary.BigEndian.Uint64(sum[:8])
+	index := hash >> (64 - hllPrecision)
+	rank := byte(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > registers[index] {
+		registers[index] = rank
+	}
+}
+
+// hllMerge Merges the registers of src into dst
+func hllMerge(dst, src []byte) {
+	for i := 0; i < len(dst) && i < len(src); i++ {
+		if src[i] > dst[i] {
+			dst[i] = src[i]
+		}
+	}
+}
+
+// hllEstimate Returns the estimated number of distinct ids added to a sketch,
+// counting the empty registers for small cardinalities
+func hllEstimate(registers []byte) uint64 {
+	m := float64(len(registers))
+	var sum float64
+	var zeros int
+	for _, r := range registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// observeUser Adds the user id of a saved session to the sketch of the current interval
+func (s *ManagerStore) observeUser(v interface{}) {
+	if v == nil {
+		return
+	}
+
+	root := s.root()
+	interval := time.Now().UTC().Truncate(root.usersInterval)
+	root.usersMu.Lock()
+	registers, ok := root.usersSketches[interval]
+	if !ok {
+		registers = make([]byte, hllRegisters)
+		root.usersSketches[interval] = registers
+	}
+	hllAdd(registers, fmt.Sprint(v))
+	root.usersMu.Unlock()
+}
+
+// flushUsers Writes the sketches of this instance, and forgets those of past intervals
+func (s *ManagerStore) flushUsers() error {
+	root := s.root()
+	root.usersMu.Lock()
+	defer root.usersMu.Unlock()
+
+	current := time.Now().UTC().Truncate(s.usersInterval)
+	for interval, registers := range root.usersSketches {
+		item := &usersItem{Interval: interval, Instance: root.instanceID, Registers: registers}
+		if err := s.users().Save(item).Error; err != nil {
+			return err
+		}
+		if interval.Before(current) {
+			delete(root.usersSketches, interval)
+		}
+	}
+	return nil
+}
+
+// UniqueUsers Estimates the number of distinct users active in the intervals
+// that start within [from, to), from the sketches of all instances
+func (s *ManagerStore) UniqueUsers(ctx context.Context, from, to time.Time) (uint64, error) {
+	if s.isClosed() {
+		return 0, ErrStoreClosed
+	} else if s.root().usersKey == "" {
+		return 0, ErrUniqueUsersDisabled
+	}
+
+	if err := checkBudget(ctx); err != nil {
+		return 0, err
+	}
+	defer charge(ctx)()
+
+	root := s.root()
+	if err := root.flushUsers(); err != nil {
+		return 0, err
+	}
+
+	var items []usersItem
+	err := root.users().Where("interval_start>=? AND interval_start<?", from.UTC(), to.UTC()).Find(&items).Error
+	if err != nil {
+		return 0, err
+	}
+
+	registers := make([]byte, hllRegisters)
+	for _, item := range items {
+		hllMerge(registers, item.Registers)
+	}
+	return hllEstimate(registers), nil
+}
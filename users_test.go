@@ -0,0 +1,63 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUniqueUsers(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	cfg := Config{TableName: "session_unique_users", UniqueUsersKey: "uid", NoBackground: true}
+	mstore, err := NewManagerStore(cfg, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	other, err := NewManagerStore(cfg, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer other.Close()
+
+	Convey("Test estimating the unique active users", t, func() {
+		ctx := context.Background()
+		So(mstore.users().Delete(&usersItem{}).Error, ShouldBeNil)
+
+		registers := make([]byte, hllRegisters)
+		for i := 0; i < 20000; i++ {
+			hllAdd(registers, fmt.Sprint(i%10000))
+		}
+		estimate := float64(hllEstimate(registers))
+		So(math.Abs(estimate-10000)/10000, ShouldBeLessThan, 0.05)
+
+		for i := 0; i < 150; i++ {
+			writer := mstore
+			if i >= 100 {
+				writer = other
+			}
+			store, err := writer.Create(ctx, newSid(), 60)
+			So(err, ShouldBeNil)
+			store.Set("uid", i%120)
+			So(store.Save(), ShouldBeNil)
+		}
+
+		So(other.GC(ctx), ShouldBeNil)
+		now := time.Now()
+		count, err := mstore.UniqueUsers(ctx, now.Add(-2*time.Hour), now.Add(time.Hour))
+		So(err, ShouldBeNil)
+		So(count, ShouldBeBetweenOrEqual, 115, 125)
+
+		count, err = mstore.UniqueUsers(ctx, now.Add(time.Hour), now.Add(2*time.Hour))
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+}
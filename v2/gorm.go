@@ -0,0 +1,424 @@
+// Package gorm is a session store for gorm.io/gorm (GORM v2),
+// the store for github.com/jinzhu/gorm is github.com/go-session/gorm
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-session/session"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	_             session.ManagerStore = &ManagerStore{}
+	_             session.Store        = &Store{}
+	jsonMarshal                        = json.Marshal
+	jsonUnmarshal                      = json.Unmarshal
+)
+
+// ErrStoreClosed Returned by operations on a store that has been closed
+var ErrStoreClosed = errors.New("gorm session store is closed")
+
+// SessionItem Data items stored in the database
+type SessionItem struct {
+	ID        string    `gorm:"column:id;size:255;primaryKey"`
+	Value     string    `gorm:"column:value;size:2048"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	ExpiredAt time.Time `gorm:"column:expired_at"`
+}
+
+// Config configuration parameter
+type Config struct {
+	Debug           bool          // start debug mode
+	ConnMaxLifetime time.Duration // sets the maximum amount of time a connection may be reused
+	MaxOpenConns    int           // sets the maximum number of open connections to the database
+	MaxIdleConns    int           // sets the maximum number of connections in the idle connection pool
+	TableName       string        // Specify the stored table name (default session)
+	GCInterval      time.Duration // Time interval for executing GC (default 10 minutes)
+}
+
+// MustStore Create an instance of a gorm store(Throw a panic if an error occurs)
+func MustStore(cfg Config, dialector gorm.Dialector) *ManagerStore {
+	store, err := NewStore(cfg, dialector)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+// NewStore Create an instance of a gorm store,
+// dialector is a driver of gorm.io, e.g. mysql.Open(dsn) of gorm.io/driver/mysql
+func NewStore(cfg Config, dialector gorm.Dialector) (*ManagerStore, error) {
+	gormCfg := &gorm.Config{}
+	if cfg.Debug {
+		gormCfg.Logger = logger.Default.LogMode(logger.Info)
+	}
+
+	db, err := gorm.Open(dialector, gormCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return NewStoreWithConfig(db, cfg)
+}
+
+// MustStoreWithDB Create an instance of a gorm store(Throw a panic if an error occurs)
+func MustStoreWithDB(db *gorm.DB, tableName string, gcInterval int) *ManagerStore {
+	store, err := NewStoreWithDB(db, tableName, gcInterval)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+// NewStoreWithDB Create an instance of a gorm store,
+// tableName Specify the stored table name (default session),
+// gcInterval Time interval for executing GC (in seconds, default 600)
+func NewStoreWithDB(db *gorm.DB, tableName string, gcInterval int) (*ManagerStore, error) {
+	return NewStoreWithConfig(db, Config{
+		TableName:  tableName,
+		GCInterval: time.Second * time.Duration(gcInterval),
+	})
+}
+
+// NewStoreWithConfig Create an instance of a gorm store on an existing db,
+// the connection pool settings of cfg are not applied
+func NewStoreWithConfig(db *gorm.DB, cfg Config) (*ManagerStore, error) {
+	store := &ManagerStore{
+		tableName: "session",
+		stdout:    os.Stderr,
+		done:      make(chan struct{}),
+	}
+
+	if cfg.TableName != "" {
+		store.tableName = cfg.TableName
+	}
+	store.db = db.Table(store.tableName).Session(&gorm.Session{})
+
+	if err := store.initTable(); err != nil {
+		return nil, err
+	}
+
+	interval := time.Minute * 10
+	if cfg.GCInterval > 0 {
+		interval = cfg.GCInterval
+	}
+	store.ticker = time.NewTicker(interval)
+
+	go store.gc()
+	return store, nil
+}
+
+// ManagerStore A gorm.io implementation of session.ManagerStore
+type ManagerStore struct {
+	ticker    *time.Ticker
+	wg        sync.WaitGroup
+	db        *gorm.DB
+	tableName string
+	stdout    io.Writer
+	done      chan struct{}
+	closed    int32
+}
+
+// initTable Migrates the table of the store and adds the expired_at index,
+// the index is named after the table as index names are global in some databases
+func (s *ManagerStore) initTable() error {
+	err := s.db.AutoMigrate(&SessionItem{})
+	if err != nil {
+		return err
+	}
+
+	index := "idx_" + s.tableName + "_expired_at"
+	if s.db.Migrator().HasIndex(s.tableName, index) {
+		return nil
+	}
+	return s.db.Exec("CREATE INDEX ? ON ?(?)",
+		clause.Table{Name: index}, clause.Table{Name: s.tableName}, clause.Column{Name: "expired_at"}).Error
+}
+
+func (s *ManagerStore) gc() {
+	for {
+		select {
+		case <-s.ticker.C:
+			s.clean()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *ManagerStore) isClosed() bool {
+	return atomic.LoadInt32(&s.closed) == 1
+}
+
+func (s *ManagerStore) clean() {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	err := s.db.Where("expired_at<=?", time.Now()).Delete(&SessionItem{}).Error
+	if err != nil {
+		s.errorf(err.Error())
+	}
+}
+
+func (s *ManagerStore) errorf(format string, args ...interface{}) {
+	if s.stdout != nil {
+		buf := fmt.Sprintf("[GORM-SESSION-ERROR]: "+format, args...)
+		s.stdout.Write([]byte(buf))
+	}
+}
+
+// withContext Returns the db of the store bound to ctx
+func (s *ManagerStore) withContext(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		return s.db
+	}
+	return s.db.WithContext(ctx)
+}
+
+func (s *ManagerStore) getValue(ctx context.Context, sid string) (string, error) {
+	var item SessionItem
+	err := s.withContext(ctx).Where("id=?", sid).Take(&item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	} else if item.ExpiredAt.Before(time.Now()) {
+		return "", nil
+	}
+	return item.Value, nil
+}
+
+func (s *ManagerStore) parseValue(value string) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if len(value) > 0 {
+		err := jsonUnmarshal([]byte(value), &values)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (s *ManagerStore) GetExpired(expired int64) time.Time {
+	return time.Now().Add(time.Duration(expired) * time.Second)
+}
+
+func (s *ManagerStore) Check(ctx context.Context, sid string) (bool, error) {
+	if s.isClosed() {
+		return false, ErrStoreClosed
+	}
+
+	var count int64
+	err := s.withContext(ctx).Where("id=?", sid).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *ManagerStore) Create(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+	return newStore(ctx, s, sid, expired, nil), nil
+}
+
+func (s *ManagerStore) Update(ctx context.Context, sid string, expired int64) (session.Store, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	value, err := s.getValue(ctx, sid)
+	if err != nil {
+		return nil, err
+	} else if value == "" {
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+
+	err = s.withContext(ctx).Where("id=?", sid).Update("expired_at", s.GetExpired(expired)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.parseValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStore(ctx, s, sid, expired, values), nil
+}
+
+func (s *ManagerStore) Delete(ctx context.Context, sid string) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+	return s.withContext(ctx).Where("id=?", sid).Delete(&SessionItem{}).Error
+}
+
+func (s *ManagerStore) Refresh(ctx context.Context, oldsid, sid string, expired int64) (session.Store, error) {
+	if s.isClosed() {
+		return nil, ErrStoreClosed
+	}
+
+	value, err := s.getValue(ctx, oldsid)
+	if err != nil {
+		return nil, err
+	} else if value == "" {
+		return newStore(ctx, s, sid, expired, nil), nil
+	}
+
+	// the new row and the removal of the old one are committed together
+	err = s.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		item := &SessionItem{
+			ID:        sid,
+			Value:     value,
+			CreatedAt: time.Now(),
+			ExpiredAt: s.GetExpired(expired),
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return tx.Where("id=?", oldsid).Delete(&SessionItem{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.parseValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStore(ctx, s, sid, expired, values), nil
+}
+
+// Close Stops the GC and closes the database,
+// calling it again does nothing and returns nil
+func (s *ManagerStore) Close() error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+
+	s.ticker.Stop()
+	close(s.done)
+	s.wg.Wait()
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func newStore(ctx context.Context, s *ManagerStore, sid string, expired int64, values map[string]interface{}) *Store {
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	return &Store{
+		ctx:     ctx,
+		mstore:  s,
+		sid:     sid,
+		expired: expired,
+		values:  values,
+	}
+}
+
+// Store A gorm.io implementation of session.Store
+type Store struct {
+	sync.RWMutex
+	ctx     context.Context
+	mstore  *ManagerStore
+	sid     string
+	expired int64
+	values  map[string]interface{}
+}
+
+func (s *Store) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Store) SessionID() string {
+	return s.sid
+}
+
+func (s *Store) Set(key string, value interface{}) {
+	s.Lock()
+	s.values[key] = value
+	s.Unlock()
+}
+
+func (s *Store) Get(key string) (interface{}, bool) {
+	s.RLock()
+	val, ok := s.values[key]
+	s.RUnlock()
+	return val, ok
+}
+
+func (s *Store) Delete(key string) interface{} {
+	s.Lock()
+	v, ok := s.values[key]
+	if ok {
+		delete(s.values, key)
+	}
+	s.Unlock()
+	return v
+}
+
+func (s *Store) Flush() error {
+	s.Lock()
+	s.values = make(map[string]interface{})
+	s.Unlock()
+	return s.Save()
+}
+
+func (s *Store) Save() error {
+	var value string
+
+	s.RLock()
+	if len(s.values) > 0 {
+		buf, err := jsonMarshal(s.values)
+		if err != nil {
+			s.RUnlock()
+			return err
+		}
+		value = string(buf)
+	}
+	s.RUnlock()
+
+	if s.mstore.isClosed() {
+		return ErrStoreClosed
+	}
+
+	// inserts the session or updates the value and expiry of the existing row
+	item := &SessionItem{
+		ID:        s.sid,
+		Value:     value,
+		CreatedAt: time.Now(),
+		ExpiredAt: s.mstore.GetExpired(s.expired),
+	}
+	return s.mstore.withContext(s.ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expired_at"}),
+	}).Create(item).Error
+}
@@ -0,0 +1,90 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSqliteStore(t *testing.T) {
+	mstore, err := NewStore(Config{TableName: "session_v2"}, sqlite.Open(os.TempDir()+"/gorm_v2.db"))
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test gorm.io storage operation", t, func() {
+		ctx := context.Background()
+		sid := "test_v2_store"
+		defer mstore.Delete(ctx, sid)
+
+		store, err := mstore.Create(ctx, sid, 300)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		store.Set("foo", "baz")
+		So(store.Save(), ShouldBeNil)
+
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeTrue)
+
+		store, err = mstore.Update(ctx, sid, 300)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "baz")
+
+		newsid := "test_v2_store_refresh"
+		defer mstore.Delete(ctx, newsid)
+		store, err = mstore.Refresh(ctx, sid, newsid, 300)
+		So(err, ShouldBeNil)
+		foo, _ = store.Get("foo")
+		So(foo, ShouldEqual, "baz")
+
+		exists, err = mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+
+		So(store.Flush(), ShouldBeNil)
+		store, err = mstore.Update(ctx, newsid, 300)
+		So(err, ShouldBeNil)
+		_, ok = store.Get("foo")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestStoreWithDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(os.TempDir()+"/gorm_v2.db"), &gorm.Config{})
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	mstore, err := NewStoreWithDB(db, "session_v2_gc", 1)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test gorm.io storage GC", t, func() {
+		ctx := context.Background()
+		sid := "test_v2_gc"
+		store, err := mstore.Create(ctx, sid, 1)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		time.Sleep(2500 * time.Millisecond)
+		exists, err := mstore.Check(ctx, sid)
+		So(err, ShouldBeNil)
+		So(exists, ShouldBeFalse)
+	})
+}
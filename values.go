@@ -0,0 +1,133 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// sessionMeta Narrow session row of a store with SeparateValues
+type sessionMeta struct {
+	ID        string    `gorm:"column:id;size:255;primary_key;"`
+	CreatedAt time.Time `gorm:"column:created_at;"`
+	ExpiredAt time.Time `gorm:"column:expired_at;"`
+}
+
+// valueItem Row of the values table, joined to the session table by id
+type valueItem struct {
+	ID    string `gorm:"column:id;size:255;primary_key;"`
+	Value string `gorm:"column:value;size:2048;"`
+}
+
+// valueModel Returns the model used to create the values table
+func valueModel(valueSize int, valueType string) interface{} {
+	return resizeValue(&valueItem{}, valueSize, valueType)
+}
+
+// valueTable Returns the name of the values table of a session table
+func valueTable(tableName string) string {
+	return tableName + "_values"
+}
+
+// values Returns the db of the values table
+func (s *ManagerStore) values() *gorm.DB {
+	return s.db.Table(valueTable(s.tableName))
+}
+
+func (s *ManagerStore) initValueTable() error {
+	table := valueTable(s.tableName)
+	if !s.db.HasTable(table) {
+		err := s.values().CreateTable(valueModel(s.valueSize, s.valueType)).Error
+		if err != nil && !s.db.HasTable(table) {
+			return err
+		}
+	} else if s.valueSize > 0 || s.valueType != "" {
+		err := s.checkValueColumn(table, s.valueSize, s.migrateValue)
+		if err != nil {
+			return err
+		}
+	}
+	return s.moveInlineValues()
+}
+
+// moveInlineValues Copies the values stored in the session table, by a store without
+// SeparateValues, to the values table, the values already there are kept
+func (s *ManagerStore) moveInlineValues() error {
+	if !s.db.Dialect().HasColumn(s.tableName, "value") {
+		return nil
+	}
+
+	scope := s.db.NewScope(nil)
+	table := scope.Quote(s.tableName)
+	values := scope.Quote(valueTable(s.tableName))
+	return s.db.Exec(fmt.Sprintf("INSERT INTO %s (id, value) SELECT id, value FROM %s "+
+		"WHERE value IS NOT NULL AND id NOT IN (SELECT id FROM %s)", values, table, values)).Error
+}
+
+// loadValue Reads the value of the item from the values table, the item keeps
+// the value of the session row if it has none there, e.g. written before SeparateValues
+func (s *ManagerStore) loadValue(item *SessionItem) error {
+	var value valueItem
+	err := s.values().Where("id=?", item.ID).First(&value).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	item.Value = value.Value
+	return nil
+}
+
+// writeValue Inserts or updates the value of key in the values table
+func (s *ManagerStore) writeValue(key, value string) error {
+	var conflict string
+	switch s.db.Dialect().GetName() {
+	case "mysql":
+		conflict = "ON DUPLICATE KEY UPDATE value=VALUES(value)"
+	case "postgres", "sqlite3":
+		conflict = "ON CONFLICT (id) DO UPDATE SET value=excluded.value"
+	}
+	if conflict != "" {
+		query := fmt.Sprintf("INSERT INTO %s (id, value) VALUES (?, ?) "+conflict,
+			s.db.NewScope(nil).Quote(valueTable(s.tableName)))
+		return s.db.Exec(query, key, value).Error
+	}
+
+	// dialects without an upsert run in the transaction of the caller
+	db := s.values()
+	result := db.Where("id=?", key).Update("value", value)
+	if err := result.Error; err != nil || result.RowsAffected > 0 {
+		return err
+	}
+
+	// some drivers count unchanged rows as unaffected
+	var count int
+	err := db.Where("id=?", key).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	return db.Create(&valueItem{ID: key, Value: value}).Error
+}
+
+// createItem Inserts the session row, and its value into the values table with SeparateValues
+func (s *ManagerStore) createItem(item *SessionItem) error {
+	if !s.separateValues {
+		return s.db.Create(item).Error
+	}
+
+	return s.atomically(context.Background(), func(tx *ManagerStore) error {
+		err := tx.db.Omit("value").Create(item).Error
+		if err != nil {
+			return err
+		}
+		return tx.writeValue(item.ID, item.Value)
+	})
+}
+
+// cleanValues Deletes the values whose session row is gone
+func (s *ManagerStore) cleanValues() error {
+	cond := fmt.Sprintf("id NOT IN (SELECT id FROM %s)", s.db.NewScope(nil).Quote(s.tableName))
+	return s.values().Where(cond).Delete(nil).Error
+}
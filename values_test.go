@@ -0,0 +1,117 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSeparateValues(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_narrow", SeparateValues: true}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test storing the values in a separate table", t, func() {
+		ctx := context.Background()
+		So(mstore.db.Dialect().HasColumn("session_narrow", "value"), ShouldBeFalse)
+		So(mstore.db.Dialect().HasColumn("session_narrow_values", "value"), ShouldBeTrue)
+
+		sid := newSid()
+		store, err := mstore.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+		store.Set("foo", "baz")
+		So(store.Save(), ShouldBeNil)
+
+		var count int
+		So(mstore.values().Where("id=?", sid).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "baz")
+
+		newsid := newSid()
+		store, err = mstore.Refresh(ctx, sid, newsid, 60)
+		So(err, ShouldBeNil)
+		foo, _ = store.Get("foo")
+		So(foo, ShouldEqual, "baz")
+		So(mstore.values().Where("id=?", sid).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+
+		// values of sessions removed by GC are cleaned up afterwards
+		So(mstore.db.Where("id=?", newsid).Delete(nil).Error, ShouldBeNil)
+		mstore.clean()
+		So(mstore.values().Where("id=?", newsid).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+}
+
+func TestSeparateValuesExistingTable(t *testing.T) {
+	dsn := os.TempDir() + "/gorm.db"
+	inline, err := NewManagerStore(Config{TableName: "session_narrow_inline"}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer inline.Close()
+
+	Convey("Test enabling SeparateValues on a table with inline values", t, func() {
+		ctx := context.Background()
+		So(inline.db.DropTableIfExists("session_narrow_inline_values").Error, ShouldBeNil)
+		sid := newSid()
+		store, err := inline.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "bar")
+		So(store.Save(), ShouldBeNil)
+
+		mstore, err := NewManagerStore(Config{TableName: "session_narrow_inline", SeparateValues: true}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		foo, ok := store.Get("foo")
+		So(ok, ShouldBeTrue)
+		So(foo, ShouldEqual, "bar")
+
+		// a row saved inline after the move is still read from the session row
+		sid = newSid()
+		store, err = inline.Create(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		store.Set("foo", "baz")
+		So(store.Save(), ShouldBeNil)
+		store, err = mstore.Update(ctx, sid, 60)
+		So(err, ShouldBeNil)
+		foo, _ = store.Get("foo")
+		So(foo, ShouldEqual, "baz")
+	})
+
+	Convey("Test concurrent writes of the same value row", t, func() {
+		mstore, err := NewManagerStore(Config{TableName: "session_narrow", SeparateValues: true}, "sqlite3", dsn)
+		So(err, ShouldBeNil)
+		defer mstore.Close()
+
+		key := newSid()
+		errs := make(chan error, 10)
+		for i := 0; i < cap(errs); i++ {
+			go func() { errs <- mstore.writeValue(key, `{"foo":"bar"}`) }()
+		}
+		for i := 0; i < cap(errs); i++ {
+			So(<-errs, ShouldBeNil)
+		}
+
+		var count int
+		So(mstore.values().Where("id=?", key).Count(&count).Error, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,93 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrInTransaction Returned by Warmup on the store of a transaction, which has no connection pool
+var ErrInTransaction = errors.New("gorm session: the operation cannot run in a transaction")
+
+// Warmup Prepares the store for traffic, e.g. at the start of an autoscaled instance:
+// it pings the database, verifies that the table has every column and index the
+// store uses, and opens conns connections of the pool (at most MaxOpenConns), which stay
+// idle up to MaxIdleConns
+func (s *ManagerStore) Warmup(ctx context.Context, conns int) error {
+	if s.isClosed() {
+		return ErrStoreClosed
+	}
+
+	defer charge(ctx)()
+	s, err := s.forContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sqlDB := s.sqlDB()
+	if sqlDB == nil {
+		return ErrInTransaction
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+
+	if err := s.verifySchema(); err != nil {
+		return err
+	}
+	return primeConns(ctx, sqlDB, conns)
+}
+
+// verifySchema Returns an error naming the first missing column or index of the table
+func (s *ManagerStore) verifySchema() error {
+	dialect := s.db.Dialect()
+	if !dialect.HasTable(s.tableName) {
+		return fmt.Errorf("gorm session: table %s does not exist", s.tableName)
+	}
+
+	model := sessionModel(s.valueSize, s.valueType)
+	if s.separateValues {
+		model = &sessionMeta{}
+	}
+	for _, m := range append([]interface{}{model}, s.columns...) {
+		if column := s.missingColumn(s.db, m); column != "" {
+			return fmt.Errorf("gorm session: column %s of table %s does not exist", column, s.tableName)
+		}
+	}
+
+	if !s.skipIndexes && !s.hasExpiredIndex() {
+		return fmt.Errorf("gorm session: index %s of table %s does not exist", expiredIndex(s.tableName), s.tableName)
+	}
+	return nil
+}
+
+// primeConns Opens n connections at once and returns them to the pool, n is capped
+// at MaxOpenConns since a pool that is full would block on the next connection
+func primeConns(ctx context.Context, db *sql.DB, n int) error {
+	if max := db.Stats().MaxOpenConnections; max > 0 && n > max {
+		n = max
+	}
+
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
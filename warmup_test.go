@@ -0,0 +1,48 @@
+package gorm
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWarmup(t *testing.T) {
+	dsn := os.TempDir() + "/gorm_warmup.db"
+	mstore, err := NewManagerStore(Config{TableName: "session_warmup", MaxIdleConns: 3, Signer: SignerFunc(hmacSign)}, "sqlite3", dsn)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	defer mstore.Close()
+
+	Convey("Test warming up the store", t, func() {
+		ctx := context.Background()
+		So(mstore.Warmup(ctx, 3), ShouldBeNil)
+		So(mstore.PoolStats().Idle, ShouldEqual, 3)
+
+		mstore.tableName = "session_warmup_missing"
+		defer func() { mstore.tableName = "session_warmup" }()
+		So(mstore.verifySchema(), ShouldNotBeNil)
+	})
+
+	Convey("Test warming up more connections than the pool allows", t, func() {
+		mstore.db.DB().SetMaxOpenConns(2)
+		defer mstore.db.DB().SetMaxOpenConns(0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		So(mstore.Warmup(ctx, 5), ShouldBeNil)
+		So(mstore.PoolStats().OpenConnections, ShouldBeLessThanOrEqualTo, 2)
+	})
+
+	Convey("Test warming up the store of a transaction", t, func() {
+		ctx := context.Background()
+		err := mstore.WithTransaction(ctx, func(tx *ManagerStore) error {
+			return tx.Warmup(ctx, 1)
+		})
+		So(err, ShouldEqual, ErrInTransaction)
+	})
+}